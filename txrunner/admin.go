@@ -0,0 +1,215 @@
+package txrunner
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/0xsequence/ethkit/ethcoder"
+	"github.com/0xsequence/ethkit/ethrpc"
+	"github.com/0xsequence/ethkit/go-ethereum"
+	"github.com/0xsequence/ethkit/go-ethereum/common"
+	"github.com/0xsequence/ethkit/go-ethereum/core/types"
+	sequence "github.com/0xsequence/go-sequence"
+)
+
+// ---------------------------------------------------------------------------
+// Wallet administration — self-calls that modify the wallet
+// ---------------------------------------------------------------------------
+
+const AdminUsage = `  set-implementation <address>     point the wallet at a new implementation
+  add-hook <selector> <address>    route calls to selector to a hook implementation
+  remove-hook <selector>           remove the hook for selector
+
+  <selector> is a 4-byte hex selector (0x150b7a02) or a function signature
+  (onERC721Received(address,address,uint256,bytes)).
+`
+
+// AdminOp is a wallet administration operation: a call the wallet makes to
+// itself, plus a read that confirms the change.
+type AdminOp struct {
+	Name        string
+	Description string
+	Data        []byte
+	// Verify checks the wallet's on-chain state after the call confirms,
+	// and describes what it found.
+	Verify func(ctx context.Context, provider *ethrpc.Provider, walletAddr common.Address) (string, error)
+}
+
+// ParseAdminOp parses the arguments following the "admin" subcommand.
+func ParseAdminOp(args []string) (*AdminOp, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("missing operation\n%s", AdminUsage)
+	}
+	name, args := args[0], args[1:]
+
+	parseAddr := func(s string) (common.Address, error) {
+		if !common.IsHexAddress(s) {
+			return common.Address{}, fmt.Errorf("invalid address %q", s)
+		}
+		return common.HexToAddress(s), nil
+	}
+
+	switch name {
+	case "set-implementation":
+		if len(args) != 1 {
+			return nil, errors.New("usage: admin set-implementation <address>")
+		}
+		impl, err := parseAddr(args[0])
+		if err != nil {
+			return nil, err
+		}
+		data, err := walletAdminABI.Pack("updateImplementation", impl)
+		if err != nil {
+			return nil, fmt.Errorf("encode updateImplementation: %w", err)
+		}
+		return &AdminOp{
+			Name:        name,
+			Description: "updateImplementation(" + impl.Hex() + ")",
+			Data:        data,
+			Verify: func(ctx context.Context, provider *ethrpc.Provider, walletAddr common.Address) (string, error) {
+				return expectWalletAddress(ctx, provider, walletAddr, "implementation", impl, "getImplementation")
+			},
+		}, nil
+
+	case "add-hook":
+		if len(args) != 2 {
+			return nil, errors.New("usage: admin add-hook <selector> <address>")
+		}
+		selector, err := parseSelector(args[0])
+		if err != nil {
+			return nil, err
+		}
+		impl, err := parseAddr(args[1])
+		if err != nil {
+			return nil, err
+		}
+		data, err := walletAdminABI.Pack("addHook", selector, impl)
+		if err != nil {
+			return nil, fmt.Errorf("encode addHook: %w", err)
+		}
+		return &AdminOp{
+			Name:        name,
+			Description: fmt.Sprintf("addHook(0x%x, %s)", selector, impl.Hex()),
+			Data:        data,
+			Verify: func(ctx context.Context, provider *ethrpc.Provider, walletAddr common.Address) (string, error) {
+				return expectWalletAddress(ctx, provider, walletAddr, fmt.Sprintf("hook for 0x%x", selector), impl, "readHook", selector)
+			},
+		}, nil
+
+	case "remove-hook":
+		if len(args) != 1 {
+			return nil, errors.New("usage: admin remove-hook <selector>")
+		}
+		selector, err := parseSelector(args[0])
+		if err != nil {
+			return nil, err
+		}
+		data, err := walletAdminABI.Pack("removeHook", selector)
+		if err != nil {
+			return nil, fmt.Errorf("encode removeHook: %w", err)
+		}
+		return &AdminOp{
+			Name:        name,
+			Description: fmt.Sprintf("removeHook(0x%x)", selector),
+			Data:        data,
+			Verify: func(ctx context.Context, provider *ethrpc.Provider, walletAddr common.Address) (string, error) {
+				return expectWalletAddress(ctx, provider, walletAddr, fmt.Sprintf("hook for 0x%x", selector), common.Address{}, "readHook", selector)
+			},
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown operation %q\n%s", name, AdminUsage)
+	}
+}
+
+// parseSelector accepts a 4-byte hex selector or a function signature, which
+// is hashed to its selector.
+func parseSelector(s string) ([4]byte, error) {
+	var selector [4]byte
+	if strings.Contains(s, "(") {
+		copy(selector[:], ethcoder.Keccak256([]byte(s))[:4])
+		return selector, nil
+	}
+	b, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	if err != nil || len(b) != 4 {
+		return selector, fmt.Errorf("invalid selector %q: want 4 hex bytes or a function signature", s)
+	}
+	copy(selector[:], b)
+	return selector, nil
+}
+
+// RunAdminOp relays op as a self-call from the wallet, waits for it to
+// confirm, and verifies the resulting on-chain state.
+func (r *Runner) RunAdminOp(ctx context.Context, opts SendOptions, op *AdminOp) error {
+	out := r.out()
+	cfg, wallet, provider := r.Config, r.Wallet, r.Provider
+	fmt.Fprintf(out, "\nSending admin operation %s...\n", op.Description)
+
+	tx := &sequence.Transaction{
+		To:            wallet.Address(),
+		Value:         big.NewInt(0),
+		GasLimit:      autoGasLimit(),
+		Data:          op.Data,
+		RevertOnError: true,
+	}
+
+	bundle, err := r.SendTransactionsWithFees(ctx, opts, sequence.Transactions{tx}, nil)
+	if err != nil {
+		return fmt.Errorf("relay: %w", err)
+	}
+	if bundle.Signed != nil {
+		return nil
+	}
+
+	receipt, err := waitForRelayedReceipt(ctx, out, cfg, provider, bundle)
+	if err != nil {
+		return WithMetaTxnID(bundle.MetaTxnID, fmt.Errorf("wait: %w", err))
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return WithMetaTxnID(bundle.MetaTxnID, fmt.Errorf("transaction %s reverted", receipt.TxHash.Hex()))
+	}
+	if link := cfg.explorerTxURL(receipt.TxHash.Hex()); link != "" {
+		fmt.Fprintf(out, "Confirmed: %s\n", link)
+	} else {
+		fmt.Fprintf(out, "Confirmed: %s\n", receipt.TxHash.Hex())
+	}
+
+	verified, err := op.Verify(ctx, provider, wallet.Address())
+	if err != nil {
+		return WithMetaTxnID(bundle.MetaTxnID, fmt.Errorf("verify: %w", err))
+	}
+	fmt.Fprintf(out, "Verified: %s\n", verified)
+	return nil
+}
+
+// expectWalletAddress calls a view method on the wallet that returns an
+// address and checks it equals want, describing the match.
+func expectWalletAddress(ctx context.Context, provider *ethrpc.Provider, walletAddr common.Address, what string, want common.Address, method string, args ...any) (string, error) {
+	calldata, err := walletAdminABI.Pack(method, args...)
+	if err != nil {
+		return "", fmt.Errorf("encode %s: %w", method, err)
+	}
+
+	output, err := provider.CallContract(ctx, ethereum.CallMsg{To: &walletAddr, Data: calldata}, nil)
+	if err != nil {
+		return "", fmt.Errorf("%s call: %w", method, err)
+	}
+
+	results, err := walletAdminABI.Unpack(method, output)
+	if err != nil {
+		return "", fmt.Errorf("decode %s: %w", method, err)
+	}
+	got, ok := results[0].(common.Address)
+	if !ok {
+		return "", fmt.Errorf("unexpected %s result type %T", method, results[0])
+	}
+
+	if got != want {
+		return "", fmt.Errorf("%s is %s, expected %s", what, got.Hex(), want.Hex())
+	}
+	return fmt.Sprintf("%s is %s", what, got.Hex()), nil
+}
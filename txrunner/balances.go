@@ -0,0 +1,260 @@
+package txrunner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/0xsequence/ethkit/ethrpc"
+	"github.com/0xsequence/ethkit/go-ethereum"
+	"github.com/0xsequence/ethkit/go-ethereum/accounts/abi"
+	"github.com/0xsequence/ethkit/go-ethereum/common"
+)
+
+// ---------------------------------------------------------------------------
+// Balance cache
+// ---------------------------------------------------------------------------
+
+// balanceCache memoizes native and ERC-20 balance lookups for a short TTL so
+// repeated fee checks for the same wallet don't hit the node every time. It
+// is safe for concurrent use. A zero TTL disables caching.
+type balanceCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[balanceKey]balanceEntry
+}
+
+// balanceKey identifies a cached balance. Token is the zero address for the
+// native balance.
+type balanceKey struct {
+	Owner common.Address
+	Token common.Address
+}
+
+type balanceEntry struct {
+	balance *big.Int
+	expires time.Time
+}
+
+func newBalanceCache(ttl time.Duration) *balanceCache {
+	return &balanceCache{
+		ttl:     ttl,
+		entries: make(map[balanceKey]balanceEntry),
+	}
+}
+
+func (c *balanceCache) nativeBalance(ctx context.Context, provider *ethrpc.Provider, owner common.Address) (*big.Int, error) {
+	return c.lookup(balanceKey{Owner: owner}, func() (*big.Int, error) {
+		return provider.BalanceAt(ctx, owner, nil)
+	})
+}
+
+func (c *balanceCache) erc20Balance(ctx context.Context, provider *ethrpc.Provider, token common.Address, owner common.Address) (*big.Int, error) {
+	return c.lookup(balanceKey{Owner: owner, Token: token}, func() (*big.Int, error) {
+		return erc20BalanceOf(ctx, provider, token, owner)
+	})
+}
+
+func (c *balanceCache) lookup(key balanceKey, fetch func() (*big.Int, error)) (*big.Int, error) {
+	if c.ttl <= 0 {
+		return fetch()
+	}
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.balance, nil
+	}
+
+	balance, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.entries[key] = balanceEntry{balance: balance, expires: time.Now().Add(c.ttl)}
+	c.mu.Unlock()
+
+	return balance, nil
+}
+
+// balanceBatch holds balances read together in one prefetch, for the
+// lookups of a single fee selection.
+type balanceBatch map[balanceKey]*big.Int
+
+// lookup returns the batched balance for key, or else fetches it.
+func (b balanceBatch) lookup(key balanceKey, fetch func() (*big.Int, error)) (*big.Int, error) {
+	if balance, ok := b[key]; ok {
+		return balance, nil
+	}
+	return fetch()
+}
+
+// prefetch reads owner's native balance and its balance of each of tokens in
+// a single Multicall3 aggregate3 call and returns them, so the lookups that
+// follow are served from the batch instead of one request each. When
+// caching is on they are cached too. A balance whose sub-call failed is
+// left out, for its lookup to fetch.
+func (c *balanceCache) prefetch(ctx context.Context, provider *ethrpc.Provider, multicall, owner common.Address, tokens []common.Address) (balanceBatch, error) {
+	keys := make([]balanceKey, 0, len(tokens)+1)
+	calls := make([]call3, 0, len(tokens)+1)
+
+	data, err := multicall3ABI.Pack("getEthBalance", owner)
+	if err != nil {
+		return nil, fmt.Errorf("encode getEthBalance: %w", err)
+	}
+	keys = append(keys, balanceKey{Owner: owner})
+	calls = append(calls, call3{Target: multicall, AllowFailure: true, CallData: data})
+	for _, token := range tokens {
+		data, err := erc20TokenABI.Pack("balanceOf", owner)
+		if err != nil {
+			return nil, fmt.Errorf("encode balanceOf: %w", err)
+		}
+		keys = append(keys, balanceKey{Owner: owner, Token: token})
+		calls = append(calls, call3{Target: token, AllowFailure: true, CallData: data})
+	}
+
+	results, err := aggregate3(ctx, provider, multicall, calls)
+	if err != nil {
+		return nil, err
+	}
+
+	batch := make(balanceBatch, len(results))
+	for i, result := range results {
+		if balance, ok := result.uint256(); ok {
+			batch[keys[i]] = balance
+		}
+	}
+	if c.ttl > 0 {
+		expires := time.Now().Add(c.ttl)
+		c.mu.Lock()
+		for key, balance := range batch {
+			c.entries[key] = balanceEntry{balance: balance, expires: expires}
+		}
+		c.mu.Unlock()
+	}
+	return batch, nil
+}
+
+// call3 and result3 mirror Multicall3's Call3 and Result structs.
+type call3 struct {
+	Target       common.Address
+	AllowFailure bool
+	CallData     []byte
+}
+
+type result3 struct {
+	Success    bool
+	ReturnData []byte
+}
+
+// uint256 returns the uint256 a sub-call returned. It reports false when the
+// sub-call failed or returned anything but one 32-byte word (e.g. an EOA
+// target returning nothing), leaving the value to an individual call.
+func (r result3) uint256() (*big.Int, bool) {
+	if !r.Success || len(r.ReturnData) != 32 {
+		return nil, false
+	}
+	return new(big.Int).SetBytes(r.ReturnData), true
+}
+
+// aggregate3 runs calls through the Multicall3 contract at multicall in a
+// single eth_call and returns one result per call.
+func aggregate3(ctx context.Context, provider *ethrpc.Provider, multicall common.Address, calls []call3) ([]result3, error) {
+	calldata, err := multicall3ABI.Pack("aggregate3", calls)
+	if err != nil {
+		return nil, fmt.Errorf("encode aggregate3: %w", err)
+	}
+	output, err := provider.CallContract(ctx, ethereum.CallMsg{To: &multicall, Data: calldata}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("aggregate3 call: %w", err)
+	}
+	unpacked, err := multicall3ABI.Unpack("aggregate3", output)
+	if err != nil {
+		return nil, fmt.Errorf("decode aggregate3: %w", err)
+	}
+	results := *abi.ConvertType(unpacked[0], new([]result3)).(*[]result3)
+	if len(results) != len(calls) {
+		return nil, fmt.Errorf("aggregate3 returned %d results for %d calls", len(results), len(calls))
+	}
+	return results, nil
+}
+
+// allowanceQuery is one token allowance to read: owner's for Spender.
+type allowanceQuery struct {
+	Token   common.Address
+	Spender common.Address
+}
+
+// multicallAllowances reads owner's allowance for each of queries in a
+// single aggregate3 call. An entry is nil when its sub-call failed, for the
+// caller to read with erc20Allowance instead.
+func multicallAllowances(ctx context.Context, provider *ethrpc.Provider, multicall, owner common.Address, queries []allowanceQuery) ([]*big.Int, error) {
+	calls := make([]call3, 0, len(queries))
+	for _, q := range queries {
+		data, err := erc20TokenABI.Pack("allowance", owner, q.Spender)
+		if err != nil {
+			return nil, fmt.Errorf("encode allowance: %w", err)
+		}
+		calls = append(calls, call3{Target: q.Token, AllowFailure: true, CallData: data})
+	}
+	results, err := aggregate3(ctx, provider, multicall, calls)
+	if err != nil {
+		return nil, err
+	}
+	allowances := make([]*big.Int, len(results))
+	for i, result := range results {
+		if allowance, ok := result.uint256(); ok {
+			allowances[i] = allowance
+		}
+	}
+	return allowances, nil
+}
+
+// invalidate drops every cached balance for owner.
+func (c *balanceCache) invalidate(owner common.Address) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.entries {
+		if key.Owner == owner {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// ---------------------------------------------------------------------------
+// ERC-20 balance helper
+// ---------------------------------------------------------------------------
+
+func erc20BalanceOf(ctx context.Context, provider *ethrpc.Provider, token common.Address, owner common.Address) (*big.Int, error) {
+	calldata, err := erc20TokenABI.Pack("balanceOf", owner)
+	if err != nil {
+		return nil, fmt.Errorf("encode erc20 balanceOf: %w", err)
+	}
+
+	output, err := provider.CallContract(ctx, ethereum.CallMsg{To: &token, Data: calldata}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("erc20 balanceOf call: %w", err)
+	}
+
+	results, err := erc20TokenABI.Unpack("balanceOf", output)
+	if err != nil {
+		return nil, fmt.Errorf("decode erc20 balanceOf: %w", err)
+	}
+
+	if len(results) == 0 {
+		return nil, errors.New("erc20 balanceOf returned no results")
+	}
+
+	balance, ok := results[0].(*big.Int)
+	if !ok {
+		return nil, fmt.Errorf("unexpected erc20 balance type %T", results[0])
+	}
+
+	return balance, nil
+}
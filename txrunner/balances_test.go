@@ -0,0 +1,130 @@
+package txrunner
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/0xsequence/ethkit/ethrpc"
+	"github.com/0xsequence/ethkit/go-ethereum/common"
+	sequence "github.com/0xsequence/go-sequence"
+)
+
+func TestPrefetchMatchesIndividualLookups(t *testing.T) {
+	ctx := context.Background()
+	node := newFakeNode(t)
+	owner := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	usdc := common.HexToAddress("0x0000000000000000000000000000000000000b01")
+	dai := common.HexToAddress("0x0000000000000000000000000000000000000b02")
+	noCode := common.HexToAddress("0x0000000000000000000000000000000000000b03")
+	node.native = big.NewInt(7_000_000_000_000_000)
+	node.balances[usdc] = big.NewInt(1_500_000)
+	node.balances[dai] = new(big.Int)
+
+	provider, err := ethrpc.NewProvider(node.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// With caching off, the batch alone serves the lookups that follow.
+	cache := newBalanceCache(0)
+	batch, err := cache.prefetch(ctx, provider, node.multicall, owner, []common.Address{usdc, dai, noCode})
+	if err != nil {
+		t.Fatalf("prefetch: %v", err)
+	}
+
+	wantNative, err := provider.BalanceAt(ctx, owner, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := batch[balanceKey{Owner: owner}]; got == nil || got.Cmp(wantNative) != 0 {
+		t.Errorf("native balance: multicall %v, BalanceAt %v", got, wantNative)
+	}
+	for _, token := range []common.Address{usdc, dai} {
+		want, err := erc20BalanceOf(ctx, provider, token, owner)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := batch[balanceKey{Owner: owner, Token: token}]; got == nil || got.Cmp(want) != 0 {
+			t.Errorf("%s balance: multicall %v, balanceOf %v", token.Hex(), got, want)
+		}
+	}
+	// balanceOf on an address without code fails on its own, so the
+	// multicall must not report a balance for it either.
+	if _, err := erc20BalanceOf(ctx, provider, noCode, owner); err == nil {
+		t.Error("balanceOf on an address without code: want an error")
+	}
+	if got, ok := batch[balanceKey{Owner: owner, Token: noCode}]; ok {
+		t.Errorf("address without code: batched %v, want nothing", got)
+	}
+
+	usdcFee := &sequence.RelayerFeeOption{
+		Token: sequence.RelayerFeeToken{Type: sequence.ERC20_TOKEN, ContractAddress: &usdc, Symbol: "USDC"},
+		Value: big.NewInt(1_000_000),
+	}
+	before := node.count("eth_call") + node.count("eth_getBalance")
+	canPay, err := hasSufficientBalance(ctx, provider, cache, batch, owner, usdcFee, big.NewInt(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !canPay {
+		t.Error("hasSufficientBalance: want true for a batched balance above the fee")
+	}
+	if after := node.count("eth_call") + node.count("eth_getBalance"); after != before {
+		t.Errorf("batched lookup sent %d request(s), want 0", after-before)
+	}
+
+	// With caching on, prefetched balances are cached for later lookups.
+	cache = newBalanceCache(time.Minute)
+	if _, err := cache.prefetch(ctx, provider, node.multicall, owner, []common.Address{usdc}); err != nil {
+		t.Fatalf("prefetch: %v", err)
+	}
+	before = node.count("eth_call")
+	if _, err := cache.erc20Balance(ctx, provider, usdc, owner); err != nil {
+		t.Fatal(err)
+	}
+	if after := node.count("eth_call"); after != before {
+		t.Errorf("cached lookup sent %d eth_call(s), want 0", after-before)
+	}
+}
+
+func TestMulticallAllowancesMatchIndividualCalls(t *testing.T) {
+	ctx := context.Background()
+	node := newFakeNode(t)
+	owner := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	usdc := common.HexToAddress("0x0000000000000000000000000000000000000b01")
+	dai := common.HexToAddress("0x0000000000000000000000000000000000000b02")
+	collector := common.HexToAddress("0x0000000000000000000000000000000000000c01")
+	node.balances[usdc] = new(big.Int)
+	node.balances[dai] = new(big.Int)
+	node.allowances[allowanceQuery{Token: usdc, Spender: collector}] = big.NewInt(250)
+
+	provider, err := ethrpc.NewProvider(node.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	queries := []allowanceQuery{
+		{Token: usdc, Spender: collector},
+		{Token: dai, Spender: collector},
+		{Token: common.HexToAddress("0x0000000000000000000000000000000000000b03"), Spender: collector},
+	}
+	got, err := multicallAllowances(ctx, provider, node.multicall, owner, queries)
+	if err != nil {
+		t.Fatalf("multicallAllowances: %v", err)
+	}
+	if len(got) != len(queries) {
+		t.Fatalf("got %d allowances for %d queries", len(got), len(queries))
+	}
+	for i, q := range queries[:2] {
+		want, err := erc20Allowance(ctx, provider, q.Token, owner, q.Spender)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got[i] == nil || got[i].Cmp(want) != 0 {
+			t.Errorf("%s allowance: multicall %v, allowance %v", q.Token.Hex(), got[i], want)
+		}
+	}
+	if got[2] != nil {
+		t.Errorf("address without code: got %v, want nil", got[2])
+	}
+}
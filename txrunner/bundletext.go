@@ -0,0 +1,130 @@
+package txrunner
+
+import (
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/0xsequence/ethkit/go-ethereum/accounts/abi"
+	"github.com/0xsequence/ethkit/go-ethereum/common"
+	sequence "github.com/0xsequence/go-sequence"
+)
+
+// ---------------------------------------------------------------------------
+// Bundle text — decoded, line-oriented bundle output for review and diffing
+// ---------------------------------------------------------------------------
+
+// BundleTextLog collects bundles in a stable text form: one field per line,
+// calldata decoded with argument names wherever a known ABI matches. Bundles
+// are written in send order regardless of the order they were relayed in, so
+// two runs with the same intent diff cleanly. It is safe for concurrent use.
+type BundleTextLog struct {
+	mu      sync.Mutex
+	entries []bundleTextEntry
+}
+
+type bundleTextEntry struct {
+	order int
+	text  string
+}
+
+// knownCallABIs are tried in order when decoding calldata.
+var knownCallABIs = []abi.ABI{mintFunction, erc20TokenABI, swapRouterABI, walletAdminABI}
+
+func (l *BundleTextLog) add(order int, label string, txs sequence.Transactions) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "bundle: %s\n", label)
+	for i, txn := range txs {
+		fmt.Fprintf(&b, "call[%d]:\n", i)
+		fmt.Fprintf(&b, "  to: %s\n", txn.To.Hex())
+		value := "0"
+		if txn.Value != nil {
+			value = txn.Value.String()
+		}
+		fmt.Fprintf(&b, "  value: %s\n", value)
+		fmt.Fprintf(&b, "  gasLimit: %s\n", formatGasLimit(txn.GasLimit))
+		fmt.Fprintf(&b, "  delegateCall: %t\n", txn.DelegateCall)
+		fmt.Fprintf(&b, "  revertOnError: %t\n", txn.RevertOnError)
+		writeDecodedCalldata(&b, txn.Data)
+	}
+
+	l.mu.Lock()
+	l.entries = append(l.entries, bundleTextEntry{order: order, text: b.String()})
+	l.mu.Unlock()
+}
+
+// writeDecodedCalldata writes the method and named arguments of data, or the
+// raw bytes when no known ABI matches.
+func writeDecodedCalldata(b *strings.Builder, data []byte) {
+	if len(data) == 0 {
+		b.WriteString("  data: 0x\n")
+		return
+	}
+	if len(data) >= 4 {
+		for _, known := range knownCallABIs {
+			method, err := known.MethodById(data[:4])
+			if err != nil {
+				continue
+			}
+			args, err := method.Inputs.Unpack(data[4:])
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(b, "  method: %s\n", method.Sig)
+			for i, input := range method.Inputs {
+				fmt.Fprintf(b, "  arg %s: %s\n", input.Name, formatABIValue(args[i]))
+			}
+			return
+		}
+	}
+	fmt.Fprintf(b, "  data: 0x%x\n", data)
+}
+
+// formatABIValue renders a decoded ABI value on a single line.
+func formatABIValue(v any) string {
+	switch v := v.(type) {
+	case common.Address:
+		return v.Hex()
+	case *big.Int:
+		return v.String()
+	case []byte:
+		return fmt.Sprintf("0x%x", v)
+	case [4]byte:
+		return fmt.Sprintf("0x%x", v)
+	case []common.Address:
+		parts := make([]string, len(v))
+		for i, addr := range v {
+			parts[i] = addr.Hex()
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// String returns the collected bundles in order.
+func (l *BundleTextLog) String() string {
+	l.mu.Lock()
+	entries := make([]bundleTextEntry, len(l.entries))
+	copy(entries, l.entries)
+	l.mu.Unlock()
+
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].order < entries[j].order })
+
+	var b strings.Builder
+	for i, entry := range entries {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(entry.text)
+	}
+	return b.String()
+}
+
+// WriteFile writes the collected bundles in order to path.
+func (l *BundleTextLog) WriteFile(path string) error {
+	return os.WriteFile(path, []byte(l.String()), 0o644)
+}
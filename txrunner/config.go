@@ -0,0 +1,1105 @@
+package txrunner
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/0xsequence/ethkit/ethcoder"
+	"github.com/0xsequence/ethkit/go-ethereum/accounts/abi"
+	"github.com/0xsequence/ethkit/go-ethereum/common"
+	sequence "github.com/0xsequence/go-sequence"
+	"github.com/0xsequence/go-sequence/contracts/gen/v3/walletstage1"
+)
+
+// ---------------------------------------------------------------------------
+// Configuration
+// ---------------------------------------------------------------------------
+
+type Config struct {
+	ProjectAccessKey string `json:"projectAccessKey"`
+	PrivateKey       string `json:"privateKey"`
+	ChainID          int64  `json:"chainId"`
+	TargetAddress    string `json:"targetAddress"`
+	NodeURL          string `json:"nodeUrl"`
+	RelayerURL       string `json:"relayerUrl"`
+	ExplorerURL      string `json:"explorerUrl"`
+	DirectoryURL     string `json:"directoryUrl,omitempty"`
+
+	// PrivateKeyFile is a file holding the hex private key, read instead of
+	// PrivateKey so the key needn't sit in the config. Only one may be set.
+	PrivateKeyFile string `json:"privateKeyFile,omitempty"`
+
+	// RequireInterfaces lists ERC-165 interface IDs, as 4-byte hex, that
+	// targetAddress must report supporting before anything is relayed to it.
+	RequireInterfaces []string `json:"requireInterfaces,omitempty"`
+
+	// MulticallAddress is a Multicall3 contract used to read fee token
+	// balances in one call. Defaults to the built-in chain's; "none"
+	// disables batching.
+	MulticallAddress string `json:"multicallAddress,omitempty"`
+
+	// ExplorerTxPath is appended to ExplorerURL to link a transaction.
+	// "{hash}" is replaced with the tx hash and "{chainId}" with the chain
+	// ID. Defaults to "/tx/{hash}".
+	ExplorerTxPath string `json:"explorerTxPath,omitempty"`
+
+	// FallbackRelayerURLs are tried in order for a bundle whose fee options
+	// can't be fetched from the primary relayer, or none of which is
+	// affordable. The bundle is relayed through the relayer that quoted it.
+	FallbackRelayerURLs []string `json:"fallbackRelayerUrls,omitempty"`
+
+	// ExpectedWalletAddress, when set, must equal the wallet address derived
+	// from the private key and wallet settings, or the run aborts.
+	ExpectedWalletAddress string `json:"expectedWalletAddress,omitempty"`
+
+	// ExpectedFeeRecipients, when set, restricts relayer fee payments to these
+	// addresses. Fee options paying anyone else are rejected. Off by default,
+	// but recommended in production so a spoofed or compromised relayer
+	// response can't redirect fees.
+	ExpectedFeeRecipients []string `json:"expectedFeeRecipients,omitempty"`
+
+	// PostVerify, when set, runs a view call after each confirmed mint and
+	// fails the mint if the decoded result doesn't match.
+	PostVerify *PostVerifyConfig `json:"postVerify,omitempty"`
+
+	// ConfirmLog, when set, confirms each mint by watching for an event from
+	// the target instead of waiting on the relayer's receipt.
+	ConfirmLog *ConfirmLogConfig `json:"confirmLog,omitempty"`
+
+	// ZeroFeeCheck flags relays that would go through for free, which on a
+	// chain that normally charges suggests a misconfiguration: "warn" prints
+	// a warning, "abort" fails the send. Empty disables the check.
+	ZeroFeeCheck string `json:"zeroFeeCheck,omitempty"`
+	// SponsoredFees declares that free relaying is intended (e.g. a gas
+	// sponsorship is set up), which silences ZeroFeeCheck.
+	SponsoredFees bool `json:"sponsoredFees,omitempty"`
+
+	// FeeTokenAllowlist, when set, restricts fee payment to these tokens,
+	// given as symbols (case-insensitive) or token addresses. Options in any
+	// other token are never used, not even as a fallback.
+	FeeTokenAllowlist []string `json:"feeTokenAllowlist,omitempty"`
+
+	// TokenDecimals maps fee token addresses to their decimals, used to
+	// display amounts instead of the relayer's value or a decimals() call.
+	TokenDecimals map[string]uint32 `json:"tokenDecimals,omitempty"`
+
+	// MaxTxValue, when set, is the most native value in wei any single
+	// transaction in a bundle may carry. Unset means unlimited.
+	MaxTxValue string `json:"maxTxValue,omitempty"`
+
+	// WalletMinNativeBalance, when set, is the native balance in wei the
+	// wallet must hold before sending, for relayers that require gas in the
+	// wallet even when the fee is paid in a token.
+	WalletMinNativeBalance string `json:"walletMinNativeBalance,omitempty"`
+	// AutoFundWallet tops the wallet up to WalletMinNativeBalance from the
+	// EOA instead of aborting.
+	AutoFundWallet bool `json:"autoFundWallet,omitempty"`
+
+	// RevokeAllowanceAfter relays approve(spender, 0) for each allowance the
+	// run's bundles granted, such as the feeAutoSwap router's, once the
+	// mints confirm, so no approval is left standing.
+	RevokeAllowanceAfter bool `json:"revokeAllowanceAfter,omitempty"`
+
+	// FeeTransferCheck simulates each bundle before signing and fails it if
+	// the ERC-20 fee transfer returns false or doesn't credit the recipient
+	// with the full fee.
+	FeeTransferCheck bool `json:"feeTransferCheck,omitempty"`
+
+	// FeePosition is where the fee payment goes in each bundle: "first"
+	// (default) or "last", after the calls it may be funded by.
+	FeePosition string `json:"feePosition,omitempty"`
+
+	// MinFeeTxGasLimit is the lowest gas limit used for the fee payment
+	// transaction when the relayer's option is missing one or quotes less.
+	MinFeeTxGasLimit uint64 `json:"minFeeTxGasLimit,omitempty"`
+
+	// ReorgCheckDelay, when set, re-checks each relayed transaction's receipt
+	// after this delay and follows it through any reorg. Unset disables it.
+	ReorgCheckDelay Duration `json:"reorgCheckDelay,omitempty"`
+
+	// MaxFeeOptionsToCheck caps how many fee options, cheapest first, have
+	// their balances checked. Zero means unlimited.
+	MaxFeeOptionsToCheck int `json:"maxFeeOptionsToCheck,omitempty"`
+
+	// FeeQuoteConcurrency, when set, makes sync mode fetch the fee options
+	// for all mints up front, this many at a time, before relaying any.
+	FeeQuoteConcurrency int `json:"feeQuoteConcurrency,omitempty"`
+
+	// FleetConcurrency bounds how many wallets fleet subcommands work on at
+	// once. Defaults to 4.
+	FleetConcurrency int `json:"fleetConcurrency,omitempty"`
+
+	// BalanceCacheTTL is how long fee-token balance lookups are reused.
+	// Defaults to 5s; "0s" disables caching.
+	BalanceCacheTTL *Duration `json:"balanceCacheTtl,omitempty"`
+
+	// Deployment gas limit for the first attempt, and retries on out-of-gas
+	// failures. Zero values use the defaults.
+	DeployGasLimit       uint64 `json:"deployGasLimit,omitempty"`
+	DeployGasBumpPercent int    `json:"deployGasBumpPercent,omitempty"`
+	DeployMaxGasLimit    uint64 `json:"deployMaxGasLimit,omitempty"`
+	DeployMaxAttempts    int    `json:"deployMaxAttempts,omitempty"`
+
+	// DeployNonce, when set, is the EOA nonce of the first deployment
+	// attempt, for deployers that also send other transactions. Retries, and
+	// runs without it, use the EOA's pending nonce.
+	DeployNonce *uint64 `json:"deployNonce,omitempty"`
+
+	// RetryUndeployed retries a relay once when the relayer rejects it
+	// because the wallet isn't deployed yet, after waiting for (or sending)
+	// the deployment.
+	RetryUndeployed bool `json:"retryUndeployed,omitempty"`
+
+	// RequireDeployed aborts instead of deploying a counterfactual wallet, for
+	// setups where deployment is managed elsewhere and the EOA must not spend
+	// gas.
+	RequireDeployed bool `json:"requireDeployed,omitempty"`
+
+	// SkipDeployFundingCheck disables the check that the deployer EOA can
+	// pay for the deployment before it is sent.
+	SkipDeployFundingCheck bool `json:"skipDeployFundingCheck,omitempty"`
+
+	// SkipBlockGasLimitCheck disables the check that a bundle's gas fits in
+	// the chain's block gas limit before it is signed.
+	SkipBlockGasLimitCheck bool `json:"skipBlockGasLimitCheck,omitempty"`
+
+	// SimulateBundleGas counts calls left at the auto gas limit in the block
+	// gas limit check by simulating each bundle from the wallet. Without it
+	// only pinned gas limits are checked.
+	SimulateBundleGas bool `json:"simulateBundleGas,omitempty"`
+
+	// DeployTxLog is a JSON file recording the latest deployment tx hash per
+	// wallet. When set, a deployment still pending from an earlier run is
+	// waited on instead of sending a duplicate.
+	DeployTxLog string `json:"deployTxLog,omitempty"`
+
+	// SkipDeploySignatureCheck disables recovering the signer and chain ID
+	// from a signed deployment before it is broadcast.
+	SkipDeploySignatureCheck bool `json:"skipDeploySignatureCheck,omitempty"`
+
+	// SignatureScheme is how the EOA signs wallet payloads: "eth_sign"
+	// (default) signs the digest with the EIP-191 prefix, "eip712" signs the
+	// EIP-712 payload digest itself.
+	SignatureScheme string `json:"signatureScheme,omitempty"`
+
+	// WalletCheckpoint is the checkpoint of the wallet's initial
+	// configuration. It feeds the config image hash, which the V3 factory uses
+	// as the CREATE2 salt, so each value yields a distinct wallet address for
+	// the same owner. Defaults to 0, the standard single-owner wallet.
+	WalletCheckpoint uint64 `json:"walletCheckpoint,omitempty"`
+
+	Retry RetryConfig `json:"retry,omitempty"`
+
+	// ReceiptStore, when set, persists a record of every relayed transaction.
+	ReceiptStore *ReceiptStoreConfig `json:"receiptStore,omitempty"`
+
+	// FeeAutoSwap, when set, lets the wallet swap a token it holds into an
+	// ERC-20 fee token it can't otherwise afford.
+	FeeAutoSwap *FeeAutoSwapConfig `json:"feeAutoSwap,omitempty"`
+
+	// WalletContext overrides individual addresses of the default V3 wallet
+	// context. Any change alters the counterfactual wallet address.
+	WalletContext *WalletContextConfig `json:"walletContext,omitempty"`
+
+	// NumberFormat controls how token amounts are printed. When unset,
+	// amounts are printed raw, in base units.
+	NumberFormat *NumberFormat `json:"numberFormat,omitempty"`
+
+	// ErrorABIs are ABI JSON files, e.g. the target contract's, whose custom
+	// errors are used to decode revert data.
+	ErrorABIs []string `json:"errorAbis,omitempty"`
+
+	// Advanced holds settings that are rarely needed outside debugging.
+	Advanced *AdvancedConfig `json:"advanced,omitempty"`
+
+	// revertErrors holds the custom errors of ErrorABIs and the wallet,
+	// keyed by selector.
+	revertErrors map[[4]byte]abi.Error
+
+	// fileKey is the contents of PrivateKeyFile, trimmed.
+	fileKey string
+	// keySource and keyFileSource name where PrivateKey and PrivateKeyFile
+	// were set, for the error reporting both.
+	keySource, keyFileSource string
+}
+
+// Signature schemes for the EOA's signatures on wallet payloads.
+const (
+	signatureSchemeEthSign = "eth_sign"
+	signatureSchemeEIP712  = "eip712"
+)
+
+// JSON-RPC request id strategies for the node connection.
+const (
+	rpcIDsSequential  = "sequential"
+	rpcIDsRandom      = "random"
+	rpcIDsCorrelation = "correlation"
+)
+
+// AdvancedConfig holds rarely needed settings.
+type AdvancedConfig struct {
+	// RPCRequestIDs is how JSON-RPC request ids sent to the node are
+	// generated: "sequential" (default), "random", or "correlation".
+	RPCRequestIDs string `json:"rpcRequestIds,omitempty"`
+	// CorrelationID seeds "correlation" ids. A random one is generated per
+	// run when empty.
+	CorrelationID string `json:"correlationId,omitempty"`
+	// CoalesceReads sends concurrent identical read-only requests (chain id,
+	// code, balances, eth_call) to the node once and shares the response.
+	CoalesceReads bool `json:"coalesceReads,omitempty"`
+}
+
+func (c *AdvancedConfig) validate() error {
+	switch c.RPCRequestIDs {
+	case "", rpcIDsSequential, rpcIDsRandom, rpcIDsCorrelation:
+	default:
+		return fmt.Errorf("unknown advanced.rpcRequestIds %q (want sequential, random or correlation)", c.RPCRequestIDs)
+	}
+	return nil
+}
+
+func (c *Config) rpcRequestIDs() string {
+	if c.Advanced == nil || c.Advanced.RPCRequestIDs == "" {
+		return rpcIDsSequential
+	}
+	return c.Advanced.RPCRequestIDs
+}
+
+// PostVerifyConfig describes a read-only call whose result proves a mint took
+// effect. Args and Expect are ABI string values; in Args, "{wallet}",
+// "{tokenId}" and "{target}" are replaced with the smart wallet address, the
+// mint's token ID, and the target address.
+type PostVerifyConfig struct {
+	// To defaults to the target address.
+	To string `json:"to,omitempty"`
+	// Method is the function signature, e.g. "balanceOf(address,uint256)".
+	Method string   `json:"method"`
+	Args   []string `json:"args,omitempty"`
+	// Returns lists the return types, e.g. "uint256" or "(uint256,bool)".
+	Returns string   `json:"returns"`
+	Expect  []string `json:"expect"`
+}
+
+func (c *PostVerifyConfig) validate() error {
+	if c.To != "" && !common.IsHexAddress(c.To) {
+		return fmt.Errorf("invalid postVerify.to: %s", c.To)
+	}
+	if c.Method == "" || c.Returns == "" || len(c.Expect) == 0 {
+		return errors.New("postVerify.method, postVerify.returns and postVerify.expect are required")
+	}
+	if _, err := ethcoder.ParseABISignature(c.Method); err != nil {
+		return fmt.Errorf("invalid postVerify.method: %w", err)
+	}
+	return nil
+}
+
+// ConfirmLogConfig describes the event that confirms a mint. Match maps event
+// argument names to expected values, in which "{wallet}", "{tokenId}" and
+// "{target}" are replaced as in PostVerifyConfig.Args.
+type ConfirmLogConfig struct {
+	// Event is the event signature with argument names, e.g.
+	// "Minted(address indexed to, uint256 id)".
+	Event string `json:"event"`
+	// Address is the emitting contract. Defaults to the target address.
+	Address string            `json:"address,omitempty"`
+	Match   map[string]string `json:"match,omitempty"`
+	// PollInterval is how often new logs are fetched. Defaults to 2s.
+	PollInterval Duration `json:"pollInterval,omitempty"`
+}
+
+func (c *ConfirmLogConfig) validate() error {
+	if c.Event == "" {
+		return errors.New("confirmLog.event is required")
+	}
+	if _, err := ethcoder.ValidateEventSig(c.Event); err != nil {
+		return fmt.Errorf("invalid confirmLog.event: %w", err)
+	}
+	if c.Address != "" && !common.IsHexAddress(c.Address) {
+		return fmt.Errorf("invalid confirmLog.address: %s", c.Address)
+	}
+	if c.PollInterval < 0 {
+		return fmt.Errorf("confirmLog.pollInterval must be >= 0, got %s", time.Duration(c.PollInterval))
+	}
+	event, _ := ethcoder.ParseABISignature(c.Event)
+	for name := range c.Match {
+		if !slices.Contains(event.ArgNames, name) {
+			return fmt.Errorf("confirmLog.match: event %s has no argument %q", event.Name, name)
+		}
+	}
+	return nil
+}
+
+func (c *ConfirmLogConfig) pollInterval() time.Duration {
+	if c.PollInterval == 0 {
+		return defaultConfirmLogPollInterval
+	}
+	return time.Duration(c.PollInterval)
+}
+
+// WalletContextConfig overrides fields of sequence.V3SequenceContext(). Empty
+// fields keep the default.
+type WalletContextConfig struct {
+	Factory              string `json:"factory,omitempty"`
+	MainModule           string `json:"mainModule,omitempty"`
+	MainModuleUpgradable string `json:"mainModuleUpgradable,omitempty"`
+	GuestModule          string `json:"guestModule,omitempty"`
+	Utils                string `json:"utils,omitempty"`
+	CreationCode         string `json:"creationCode,omitempty"`
+}
+
+func (c *WalletContextConfig) validate() error {
+	for name, addr := range map[string]string{
+		"factory":              c.Factory,
+		"mainModule":           c.MainModule,
+		"mainModuleUpgradable": c.MainModuleUpgradable,
+		"guestModule":          c.GuestModule,
+		"utils":                c.Utils,
+	} {
+		if addr != "" && !common.IsHexAddress(addr) {
+			return fmt.Errorf("invalid walletContext.%s: %s", name, addr)
+		}
+	}
+	return nil
+}
+
+// NumberFormat describes how token amounts are rendered for humans. A nil
+// *NumberFormat, or one with Raw set, prints raw base-unit integers.
+type NumberFormat struct {
+	// ThousandsSeparator groups the integer digits, e.g. "," or " ". Empty
+	// disables grouping.
+	ThousandsSeparator string `json:"thousandsSeparator,omitempty"`
+	// DecimalSeparator defaults to ".".
+	DecimalSeparator string `json:"decimalSeparator,omitempty"`
+	// Decimals is the number of fractional digits shown. Amounts are
+	// truncated, never rounded up. Defaults to 6.
+	Decimals *int `json:"decimals,omitempty"`
+	// Raw prints base-unit integers, for machine consumption.
+	Raw bool `json:"raw,omitempty"`
+
+	// nativeSymbol names the native token, from the chain registry.
+	nativeSymbol string
+}
+
+// FeeAutoSwapConfig configures swapping into a fee token through a Uniswap
+// V2-compatible router when no fee option is affordable outright.
+type FeeAutoSwapConfig struct {
+	// Router is the address of the Uniswap V2-compatible router.
+	Router string `json:"router"`
+	// FromToken is the ERC-20 the wallet holds and is willing to sell.
+	FromToken string `json:"fromToken"`
+	// SlippageBps is the tolerance, in basis points, added on top of the
+	// router's quoted input amount. Defaults to 50 (0.5%).
+	SlippageBps int `json:"slippageBps,omitempty"`
+}
+
+func (c *FeeAutoSwapConfig) validate() error {
+	if !common.IsHexAddress(c.Router) {
+		return fmt.Errorf("invalid feeAutoSwap.router: %q", c.Router)
+	}
+	if !common.IsHexAddress(c.FromToken) {
+		return fmt.Errorf("invalid feeAutoSwap.fromToken: %q", c.FromToken)
+	}
+	if c.SlippageBps < 0 || c.SlippageBps >= 10_000 {
+		return fmt.Errorf("feeAutoSwap.slippageBps must be between 0 and 9999, got %d", c.SlippageBps)
+	}
+	return nil
+}
+
+func (c *FeeAutoSwapConfig) slippageBps() int64 {
+	if c.SlippageBps == 0 {
+		return defaultFeeSwapSlippageBps
+	}
+	return int64(c.SlippageBps)
+}
+
+// ReceiptStoreConfig selects and configures the receipt storage backend.
+type ReceiptStoreConfig struct {
+	// Type is "file" (append JSON lines to Path, the default), "sqlite" (a
+	// table in the SQLite database at Path, in binaries built with the
+	// sqlite tag) or "s3" (one object per record in Bucket).
+	Type string `json:"type,omitempty"`
+	// Path is the file or database; the SQLite store defaults to
+	// receipts.db.
+	Path string `json:"path,omitempty"`
+	// BufferSize is how many records may wait to be written before new
+	// ones are dropped. Defaults to 256.
+	BufferSize int `json:"bufferSize,omitempty"`
+
+	Bucket string `json:"bucket,omitempty"`
+	Region string `json:"region,omitempty"`
+	Prefix string `json:"prefix,omitempty"`
+	// Endpoint overrides the S3 endpoint for S3-compatible services, using
+	// path-style requests (e.g. "http://localhost:9000").
+	Endpoint string `json:"endpoint,omitempty"`
+}
+
+func (c *ReceiptStoreConfig) validate() error {
+	switch c.Type {
+	case "", "file":
+		if c.Path == "" {
+			return errors.New("receiptStore.path is required for the file store")
+		}
+	case "sqlite":
+		if !sqliteReceiptStoreBuilt {
+			return errSQLiteNotBuilt
+		}
+	case "s3":
+		if c.Bucket == "" || c.Region == "" {
+			return errors.New("receiptStore.bucket and receiptStore.region are required for the s3 store")
+		}
+	default:
+		return fmt.Errorf("unknown receiptStore.type %q (want file, sqlite or s3)", c.Type)
+	}
+	if c.BufferSize < 0 {
+		return fmt.Errorf("receiptStore.bufferSize must be >= 0, got %d", c.BufferSize)
+	}
+	return nil
+}
+
+// RetryConfig controls retries of transient startup and network failures.
+// Zero values use the defaults.
+type RetryConfig struct {
+	// ConnectAttempts is the number of attempts to connect the wallet to the
+	// provider and relayer before giving up.
+	ConnectAttempts int `json:"connectAttempts,omitempty"`
+	// ConnectBackoff is the delay before the first connect retry. It doubles
+	// after every failed attempt.
+	ConnectBackoff Duration `json:"connectBackoff,omitempty"`
+	// ReceiptAttempts is the number of times the node is asked for a
+	// transaction receipt after the relayer reports it mined.
+	ReceiptAttempts int `json:"receiptAttempts,omitempty"`
+	// ReceiptBackoff is the delay before the first receipt retry. It doubles
+	// after every miss.
+	ReceiptBackoff Duration `json:"receiptBackoff,omitempty"`
+}
+
+func (r RetryConfig) connectAttempts() int {
+	if r.ConnectAttempts == 0 {
+		return defaultConnectAttempts
+	}
+	return r.ConnectAttempts
+}
+
+func (r RetryConfig) connectBackoff() time.Duration {
+	if r.ConnectBackoff == 0 {
+		return defaultConnectBackoff
+	}
+	return time.Duration(r.ConnectBackoff)
+}
+
+func (r RetryConfig) receiptAttempts() int {
+	if r.ReceiptAttempts == 0 {
+		return defaultReceiptAttempts
+	}
+	return r.ReceiptAttempts
+}
+
+func (r RetryConfig) receiptBackoff() time.Duration {
+	if r.ReceiptBackoff == 0 {
+		return defaultReceiptBackoff
+	}
+	return time.Duration(r.ReceiptBackoff)
+}
+
+// Duration is a time.Duration that decodes from JSON strings such as "500ms"
+// or "2s".
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"2s\": %w", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Validate reports the first problem with c: a missing required field, a
+// malformed value, or settings that conflict. LoadConfig and ParseConfig
+// call it; code that builds a Config itself should too.
+func (c *Config) Validate() error {
+	var missing []string
+	if c.ProjectAccessKey == "" {
+		missing = append(missing, "projectAccessKey")
+	}
+	if c.PrivateKey != "" && c.PrivateKeyFile != "" {
+		return fmt.Errorf("private key set by both %s (%s) and %s %s (%s); use one",
+			cmp.Or(c.keySource, "privateKey"), keyAddress(c.PrivateKey),
+			cmp.Or(c.keyFileSource, "privateKeyFile"), c.PrivateKeyFile, keyAddress(c.fileKey))
+	}
+	if c.privateKey() == "" {
+		missing = append(missing, "privateKey")
+	}
+	if c.ChainID == 0 {
+		missing = append(missing, "chainId")
+	}
+	if c.TargetAddress == "" {
+		missing = append(missing, "targetAddress")
+	}
+	if c.NodeURL == "" {
+		missing = append(missing, "nodeUrl")
+	}
+	if c.RelayerURL == "" {
+		missing = append(missing, "relayerUrl")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing required config values: %s", strings.Join(missing, ", "))
+	}
+	if !common.IsHexAddress(c.TargetAddress) {
+		return fmt.Errorf("invalid target address: %s", c.TargetAddress)
+	}
+	for _, id := range c.RequireInterfaces {
+		if _, err := parseInterfaceID(id); err != nil {
+			return fmt.Errorf("requireInterfaces: %w", err)
+		}
+	}
+	if _, err := normalizePrivateKey(c.privateKey()); err != nil {
+		if c.PrivateKeyFile != "" {
+			// The error may quote the key; name the file instead.
+			return fmt.Errorf("privateKeyFile %s does not hold a 32-byte hex private key", c.PrivateKeyFile)
+		}
+		return err
+	}
+	if c.DeployGasLimit > c.deployMaxGasLimit() {
+		return fmt.Errorf("deployGasLimit %d exceeds deployMaxGasLimit %d", c.DeployGasLimit, c.deployMaxGasLimit())
+	}
+	if c.DeployGasBumpPercent < 0 {
+		return fmt.Errorf("deployGasBumpPercent must be >= 0, got %d", c.DeployGasBumpPercent)
+	}
+	if c.DeployMaxAttempts < 0 {
+		return fmt.Errorf("deployMaxAttempts must be >= 0, got %d", c.DeployMaxAttempts)
+	}
+	if c.ReorgCheckDelay < 0 {
+		return fmt.Errorf("reorgCheckDelay must be >= 0, got %s", time.Duration(c.ReorgCheckDelay))
+	}
+	if c.PostVerify != nil {
+		if err := c.PostVerify.validate(); err != nil {
+			return err
+		}
+	}
+	if c.ConfirmLog != nil {
+		if err := c.ConfirmLog.validate(); err != nil {
+			return err
+		}
+	}
+	if c.Advanced != nil {
+		if err := c.Advanced.validate(); err != nil {
+			return err
+		}
+	}
+	if c.MaxTxValue != "" {
+		if v, ok := new(big.Int).SetString(c.MaxTxValue, 10); !ok || v.Sign() < 0 {
+			return fmt.Errorf("maxTxValue must be a non-negative integer amount of wei, got %q", c.MaxTxValue)
+		}
+	}
+	if c.WalletMinNativeBalance != "" {
+		if v, ok := new(big.Int).SetString(c.WalletMinNativeBalance, 10); !ok || v.Sign() < 0 {
+			return fmt.Errorf("walletMinNativeBalance must be a non-negative integer amount of wei, got %q", c.WalletMinNativeBalance)
+		}
+	} else if c.AutoFundWallet {
+		return errors.New("autoFundWallet requires walletMinNativeBalance")
+	}
+	switch c.SignatureScheme {
+	case "", signatureSchemeEthSign, signatureSchemeEIP712:
+	default:
+		return fmt.Errorf("unknown signatureScheme %q (want %s or %s)", c.SignatureScheme, signatureSchemeEthSign, signatureSchemeEIP712)
+	}
+	switch c.FeePosition {
+	case "", feePositionFirst, feePositionLast:
+	default:
+		return fmt.Errorf("unknown feePosition %q (want first or last)", c.FeePosition)
+	}
+	switch c.ZeroFeeCheck {
+	case "", "warn", "abort":
+	default:
+		return fmt.Errorf("unknown zeroFeeCheck %q (want warn or abort)", c.ZeroFeeCheck)
+	}
+	if c.MaxFeeOptionsToCheck < 0 {
+		return fmt.Errorf("maxFeeOptionsToCheck must be >= 0, got %d", c.MaxFeeOptionsToCheck)
+	}
+	if c.FeeQuoteConcurrency < 0 {
+		return fmt.Errorf("feeQuoteConcurrency must be >= 0, got %d", c.FeeQuoteConcurrency)
+	}
+	if c.FleetConcurrency < 0 {
+		return fmt.Errorf("fleetConcurrency must be >= 0, got %d", c.FleetConcurrency)
+	}
+	if c.BalanceCacheTTL != nil && *c.BalanceCacheTTL < 0 {
+		return fmt.Errorf("balanceCacheTtl must be >= 0, got %s", time.Duration(*c.BalanceCacheTTL))
+	}
+	if c.Retry.ConnectAttempts < 0 {
+		return fmt.Errorf("retry.connectAttempts must be >= 0, got %d", c.Retry.ConnectAttempts)
+	}
+	if c.Retry.ConnectBackoff < 0 {
+		return fmt.Errorf("retry.connectBackoff must be >= 0, got %s", time.Duration(c.Retry.ConnectBackoff))
+	}
+	if c.Retry.ReceiptAttempts < 0 {
+		return fmt.Errorf("retry.receiptAttempts must be >= 0, got %d", c.Retry.ReceiptAttempts)
+	}
+	if c.Retry.ReceiptBackoff < 0 {
+		return fmt.Errorf("retry.receiptBackoff must be >= 0, got %s", time.Duration(c.Retry.ReceiptBackoff))
+	}
+	for _, url := range c.FallbackRelayerURLs {
+		if strings.TrimSpace(url) == "" {
+			return errors.New("fallbackRelayerUrls entries must not be empty")
+		}
+	}
+	for _, entry := range c.FeeTokenAllowlist {
+		if strings.TrimSpace(entry) == "" {
+			return errors.New("feeTokenAllowlist entries must not be empty")
+		}
+	}
+	for addr, decimals := range c.TokenDecimals {
+		if !common.IsHexAddress(addr) {
+			return fmt.Errorf("invalid tokenDecimals address: %s", addr)
+		}
+		if decimals > 77 {
+			return fmt.Errorf("tokenDecimals for %s must be <= 77, got %d", addr, decimals)
+		}
+	}
+	if c.MulticallAddress != "" && c.MulticallAddress != multicallDisabled && !common.IsHexAddress(c.MulticallAddress) {
+		return fmt.Errorf("invalid multicallAddress: %s (want an address or %q)", c.MulticallAddress, multicallDisabled)
+	}
+	if c.ExplorerTxPath != "" && !strings.Contains(c.ExplorerTxPath, "{hash}") {
+		return fmt.Errorf("explorerTxPath %q must contain {hash}", c.ExplorerTxPath)
+	}
+	if c.ExpectedWalletAddress != "" && !common.IsHexAddress(c.ExpectedWalletAddress) {
+		return fmt.Errorf("invalid expectedWalletAddress: %s", c.ExpectedWalletAddress)
+	}
+	for _, addr := range c.ExpectedFeeRecipients {
+		if !common.IsHexAddress(addr) {
+			return fmt.Errorf("invalid expected fee recipient: %s", addr)
+		}
+	}
+	if c.ReceiptStore != nil {
+		if err := c.ReceiptStore.validate(); err != nil {
+			return err
+		}
+	}
+	if c.FeeAutoSwap != nil {
+		if err := c.FeeAutoSwap.validate(); err != nil {
+			return err
+		}
+	}
+	if c.WalletContext != nil {
+		if err := c.WalletContext.validate(); err != nil {
+			return err
+		}
+	}
+	if c.NumberFormat != nil && c.NumberFormat.Decimals != nil && *c.NumberFormat.Decimals < 0 {
+		return fmt.Errorf("numberFormat.decimals must be >= 0, got %d", *c.NumberFormat.Decimals)
+	}
+	return nil
+}
+
+// walletContext returns the V3 wallet context with any configured overrides
+// applied, and whether any field was overridden.
+func (c *Config) walletContext() (sequence.WalletContext, bool) {
+	wc := sequence.V3SequenceContext()
+	o := c.WalletContext
+	if o == nil {
+		return wc, false
+	}
+
+	overridden := false
+	setAddr := func(dst *common.Address, v string) {
+		if v == "" {
+			return
+		}
+		if addr := common.HexToAddress(v); addr != *dst {
+			*dst = addr
+			overridden = true
+		}
+	}
+	setAddr(&wc.FactoryAddress, o.Factory)
+	setAddr(&wc.MainModuleAddress, o.MainModule)
+	setAddr(&wc.MainModuleUpgradableAddress, o.MainModuleUpgradable)
+	setAddr(&wc.GuestModuleAddress, o.GuestModule)
+	setAddr(&wc.UtilsAddress, o.Utils)
+	if o.CreationCode != "" && o.CreationCode != wc.CreationCode {
+		wc.CreationCode = o.CreationCode
+		overridden = true
+	}
+	return wc, overridden
+}
+
+func (c *Config) balanceCacheTTL() time.Duration {
+	if c.BalanceCacheTTL == nil {
+		return defaultBalanceCacheTTL
+	}
+	return time.Duration(*c.BalanceCacheTTL)
+}
+
+// maxTxValue returns the per-transaction value cap, or nil when unlimited.
+func (c *Config) maxTxValue() *big.Int {
+	if c.MaxTxValue == "" {
+		return nil
+	}
+	v, _ := new(big.Int).SetString(c.MaxTxValue, 10)
+	return v
+}
+
+func (c *Config) walletMinNativeBalance() *big.Int {
+	if c.WalletMinNativeBalance == "" {
+		return nil
+	}
+	v, _ := new(big.Int).SetString(c.WalletMinNativeBalance, 10)
+	return v
+}
+
+// multicall returns the Multicall3 contract to batch reads through, if any.
+func (c *Config) multicall() (common.Address, bool) {
+	if c.MulticallAddress == "" || c.MulticallAddress == multicallDisabled {
+		return common.Address{}, false
+	}
+	return common.HexToAddress(c.MulticallAddress), true
+}
+
+// explorerTxURL returns the explorer link for txHash, or "" when no
+// explorer is configured.
+func (c *Config) explorerTxURL(txHash string) string {
+	if c.ExplorerURL == "" {
+		return ""
+	}
+	path := c.ExplorerTxPath
+	if path == "" {
+		path = defaultExplorerTxPath
+	}
+	path = strings.NewReplacer("{hash}", txHash, "{chainId}", strconv.FormatInt(c.ChainID, 10)).Replace(path)
+	return strings.TrimSuffix(c.ExplorerURL, "/") + "/" + strings.TrimPrefix(path, "/")
+}
+
+func (c *Config) feePosition() string {
+	if c.FeePosition == "" {
+		return feePositionFirst
+	}
+	return c.FeePosition
+}
+
+func (c *Config) fleetConcurrency() int {
+	if c.FleetConcurrency == 0 {
+		return defaultFleetConcurrency
+	}
+	return c.FleetConcurrency
+}
+
+func (c *Config) deployMaxAttempts() int {
+	if c.DeployMaxAttempts == 0 {
+		return defaultDeployMaxAttempts
+	}
+	return c.DeployMaxAttempts
+}
+
+func (c *Config) deployGasLimit() uint64 {
+	if c.DeployGasLimit == 0 {
+		return defaultDeployGasLimit
+	}
+	return c.DeployGasLimit
+}
+
+func (c *Config) deployMaxGasLimit() uint64 {
+	if c.DeployMaxGasLimit == 0 {
+		return defaultDeployMaxGasLimit
+	}
+	return c.DeployMaxGasLimit
+}
+
+// bumpDeployGasLimit returns the next deployment gas limit after an
+// out-of-gas failure, clamped to the configured cap. It returns false when
+// the current limit is already at the cap.
+func (c *Config) bumpDeployGasLimit(current uint64) (uint64, bool) {
+	percent := c.DeployGasBumpPercent
+	if percent == 0 {
+		percent = defaultDeployGasBumpPercent
+	}
+
+	limit := c.deployMaxGasLimit()
+	if current >= limit {
+		return current, false
+	}
+
+	next := current + current*uint64(percent)/100
+	if next > limit {
+		next = limit
+	}
+	return next, true
+}
+
+// tokenDecimals returns the decimals configured for token in TokenDecimals.
+func (c *Config) tokenDecimals(token common.Address) (uint32, bool) {
+	for addr, decimals := range c.TokenDecimals {
+		if common.HexToAddress(addr) == token {
+			return decimals, true
+		}
+	}
+	return 0, false
+}
+
+// isAllowedFeeToken reports whether option's token is on FeeTokenAllowlist.
+// Every token is allowed when the list is empty. The native token matches its
+// symbol, the zero address, or a native sentinel address.
+func (c *Config) isAllowedFeeToken(option *sequence.RelayerFeeOption) bool {
+	if len(c.FeeTokenAllowlist) == 0 {
+		return true
+	}
+	for _, entry := range c.FeeTokenAllowlist {
+		if !common.IsHexAddress(entry) {
+			if strings.EqualFold(entry, option.Token.Symbol) {
+				return true
+			}
+			continue
+		}
+		addr := common.HexToAddress(entry)
+		if isNativeFeeOption(option) {
+			if isNativeTokenAddress(addr) {
+				return true
+			}
+		} else if option.Token.ContractAddress != nil && *option.Token.ContractAddress == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// isExpectedFeeRecipient reports whether fees may be paid to addr. Any
+// recipient is accepted when ExpectedFeeRecipients is empty.
+func (c *Config) isExpectedFeeRecipient(addr common.Address) bool {
+	if len(c.ExpectedFeeRecipients) == 0 {
+		return true
+	}
+	for _, expected := range c.ExpectedFeeRecipients {
+		if common.HexToAddress(expected) == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadConfig reads the config at path and prepares it with ParseConfig.
+func LoadConfig(path, privateKeyFile string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseConfig(b, path, privateKeyFile)
+}
+
+// ParseConfig decodes the JSON config in data, applies the environment
+// overrides, and validates it. name identifies the config in errors, e.g.
+// its path. A non-empty privateKeyFile overrides the config's.
+func ParseConfig(data []byte, name, privateKeyFile string) (*Config, error) {
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse config: %w", err)
+	}
+	if cfg.PrivateKey != "" {
+		cfg.keySource = "privateKey in " + name
+	}
+	if cfg.PrivateKeyFile != "" {
+		cfg.keyFileSource = "privateKeyFile in " + name
+	}
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+	if privateKeyFile != "" {
+		cfg.PrivateKeyFile = privateKeyFile
+		cfg.keyFileSource = "-private-key-file"
+	}
+	// The file is read even with privateKey set, so that Validate can name
+	// the address of each key it reports as conflicting.
+	if cfg.PrivateKeyFile != "" {
+		key, err := os.ReadFile(cfg.PrivateKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("privateKeyFile: %w", err)
+		}
+		cfg.fileKey = strings.TrimSpace(string(key))
+	}
+	cfg.applyChainDefaults()
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if err := cfg.loadErrorABIs(); err != nil {
+		return nil, err
+	}
+
+	return &cfg, nil
+}
+
+// envChainID overrides chainId; it must be a decimal integer.
+const envChainID = "SEQUENCE_CHAIN_ID"
+
+// envPrivateKey overrides privateKey. It is checked as it is read, so a bad
+// value is reported against the variable rather than the config file.
+const envPrivateKey = "SEQUENCE_PRIVATE_KEY"
+
+// configEnvVars lists the environment variables that override string config
+// fields, and the field each one sets.
+var configEnvVars = []struct {
+	name  string
+	field func(c *Config) *string
+}{
+	{"SEQUENCE_PROJECT_ACCESS_KEY", func(c *Config) *string { return &c.ProjectAccessKey }},
+	{envPrivateKey, func(c *Config) *string { return &c.PrivateKey }},
+	{"SEQUENCE_TARGET_ADDRESS", func(c *Config) *string { return &c.TargetAddress }},
+	{"SEQUENCE_NODE_URL", func(c *Config) *string { return &c.NodeURL }},
+	{"SEQUENCE_RELAYER_URL", func(c *Config) *string { return &c.RelayerURL }},
+	{"SEQUENCE_EXPLORER_URL", func(c *Config) *string { return &c.ExplorerURL }},
+	{"SEQUENCE_DIRECTORY_URL", func(c *Config) *string { return &c.DirectoryURL }},
+}
+
+// applyEnv overrides config fields with the environment variables in
+// configEnvVars and envChainID that are set and non-empty, so secrets can
+// be kept out of the config file: the environment takes precedence over the
+// file. Chain defaults are applied afterwards, so they follow an overridden
+// chain ID.
+func (c *Config) applyEnv() error {
+	if value := os.Getenv(envPrivateKey); value != "" {
+		if _, err := normalizePrivateKey(value); err != nil {
+			return fmt.Errorf("%s: %w", envPrivateKey, err)
+		}
+		c.keySource = envPrivateKey
+	}
+	for _, v := range configEnvVars {
+		if value := os.Getenv(v.name); value != "" {
+			*v.field(c) = value
+		}
+	}
+	if value := os.Getenv(envChainID); value != "" {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%s: must be a decimal chain ID, got %q", envChainID, value)
+		}
+		c.ChainID = id
+	}
+	return nil
+}
+
+// privateKey returns the signer's private key, from PrivateKey or
+// PrivateKeyFile.
+func (c *Config) privateKey() string {
+	if c.PrivateKey != "" {
+		return c.PrivateKey
+	}
+	return c.fileKey
+}
+
+// loadErrorABIs registers the custom errors of the V3 wallet and of each file
+// in c.ErrorABIs. A file may hold a bare ABI array or a build artifact with
+// an "abi" field.
+func (c *Config) loadErrorABIs() error {
+	c.revertErrors = make(map[[4]byte]abi.Error)
+
+	wallet, err := abi.JSON(strings.NewReader(walletstage1.WalletStage1ABI))
+	if err != nil {
+		return fmt.Errorf("parse wallet ABI: %w", err)
+	}
+	sources := []abi.ABI{wallet}
+
+	for _, path := range c.ErrorABIs {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("errorAbis: %w", err)
+		}
+		var artifact struct {
+			ABI json.RawMessage `json:"abi"`
+		}
+		if json.Unmarshal(b, &artifact) == nil && len(artifact.ABI) > 0 {
+			b = artifact.ABI
+		}
+		parsed, err := abi.JSON(bytes.NewReader(b))
+		if err != nil {
+			return fmt.Errorf("errorAbis: parse %s: %w", path, err)
+		}
+		sources = append(sources, parsed)
+	}
+
+	for _, source := range sources {
+		for _, e := range source.Errors {
+			c.revertErrors[[4]byte(e.ID[:4])] = e
+		}
+	}
+	return nil
+}
+
+// chainInfo holds the built-in endpoints of a well-known chain.
+type chainInfo struct {
+	Name         string
+	NativeSymbol string
+	NodeURL      string
+	RelayerURL   string
+	ExplorerURL  string
+	// Multicall is the chain's Multicall3 contract.
+	Multicall common.Address
+}
+
+// newSequenceChain returns the chainInfo for a chain served by Sequence's
+// hosted node and relayer under name.
+func newSequenceChain(name, nativeSymbol, explorerURL string) chainInfo {
+	return chainInfo{
+		Name:         name,
+		NativeSymbol: nativeSymbol,
+		NodeURL:      "https://nodes.sequence.app/" + name,
+		RelayerURL:   "https://" + name + "-relayer.sequence.app",
+		ExplorerURL:  explorerURL,
+		Multicall:    multicall3Address,
+	}
+}
+
+// knownChains maps chain IDs to their built-in endpoints.
+var knownChains = map[int64]chainInfo{
+	1:        newSequenceChain("mainnet", "ETH", "https://etherscan.io"),
+	10:       newSequenceChain("optimism", "ETH", "https://optimistic.etherscan.io"),
+	56:       newSequenceChain("bsc", "BNB", "https://bscscan.com"),
+	100:      newSequenceChain("gnosis", "XDAI", "https://gnosisscan.io"),
+	137:      newSequenceChain("polygon", "POL", "https://polygonscan.com"),
+	8453:     newSequenceChain("base", "ETH", "https://basescan.org"),
+	42161:    newSequenceChain("arbitrum", "ETH", "https://arbiscan.io"),
+	42170:    newSequenceChain("arbitrum-nova", "ETH", "https://nova.arbiscan.io"),
+	43114:    newSequenceChain("avalanche", "AVAX", "https://snowtrace.io"),
+	80002:    newSequenceChain("amoy", "POL", "https://amoy.polygonscan.com"),
+	84532:    newSequenceChain("base-sepolia", "ETH", "https://sepolia.basescan.org"),
+	421614:   newSequenceChain("arbitrum-sepolia", "ETH", "https://sepolia.arbiscan.io"),
+	11155111: newSequenceChain("sepolia", "ETH", "https://sepolia.etherscan.io"),
+}
+
+// applyChainDefaults fills nodeUrl, relayerUrl, and explorerUrl from the
+// built-in registry when they are unset and the chain is known, and names the
+// native token in formatted amounts.
+func (c *Config) applyChainDefaults() {
+	chain, ok := knownChains[c.ChainID]
+	if !ok {
+		return
+	}
+	if c.NumberFormat != nil {
+		c.NumberFormat.nativeSymbol = chain.NativeSymbol
+	}
+	if c.NodeURL == "" {
+		c.NodeURL = chain.NodeURL
+	}
+	if c.RelayerURL == "" {
+		c.RelayerURL = chain.RelayerURL
+	}
+	if c.ExplorerURL == "" {
+		c.ExplorerURL = chain.ExplorerURL
+	}
+	if c.MulticallAddress == "" && chain.Multicall != (common.Address{}) {
+		c.MulticallAddress = chain.Multicall.Hex()
+	}
+}
@@ -0,0 +1,60 @@
+package txrunner
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseConfig(t *testing.T) {
+	for _, name := range []string{"SEQUENCE_CHAIN_ID", "SEQUENCE_PROJECT_ACCESS_KEY", envPrivateKey, "SEQUENCE_TARGET_ADDRESS", "SEQUENCE_NODE_URL", "SEQUENCE_RELAYER_URL", "SEQUENCE_EXPLORER_URL", "SEQUENCE_DIRECTORY_URL"} {
+		t.Setenv(name, "")
+	}
+	const key = "0x4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318"
+
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{
+			name: "built-in chain fills in urls",
+			data: `{"chainId": 421614, "projectAccessKey": "x", "privateKey": "` + key + `", "targetAddress": "0x0000000000000000000000000000000000000001"}`,
+		},
+		{
+			name:    "missing fields are listed",
+			data:    `{"chainId": 421614, "privateKey": "` + key + `"}`,
+			wantErr: "missing required config values: projectAccessKey, targetAddress",
+		},
+		{
+			name:    "unknown chain needs urls",
+			data:    `{"chainId": 999999, "projectAccessKey": "x", "privateKey": "` + key + `", "targetAddress": "0x0000000000000000000000000000000000000001"}`,
+			wantErr: "missing required config values: nodeUrl, relayerUrl",
+		},
+		{
+			name:    "not json",
+			data:    `chainId: 1`,
+			wantErr: "parse config",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := ParseConfig([]byte(tt.data), "test.json", "")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.NodeURL == "" || cfg.RelayerURL == "" {
+				t.Errorf("chain defaults not applied: nodeUrl %q, relayerUrl %q", cfg.NodeURL, cfg.RelayerURL)
+			}
+			// A parsed config stays valid when checked again.
+			if err := cfg.Validate(); err != nil {
+				t.Errorf("Validate: %v", err)
+			}
+		})
+	}
+}
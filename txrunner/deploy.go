@@ -0,0 +1,571 @@
+package txrunner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/0xsequence/ethkit/ethrpc"
+	"github.com/0xsequence/ethkit/ethtxn"
+	"github.com/0xsequence/ethkit/ethwallet"
+	"github.com/0xsequence/ethkit/go-ethereum"
+	"github.com/0xsequence/ethkit/go-ethereum/common"
+	"github.com/0xsequence/ethkit/go-ethereum/core/types"
+	sequence "github.com/0xsequence/go-sequence"
+	v3 "github.com/0xsequence/go-sequence/core/v3"
+	"github.com/0xsequence/go-sequence/services/keymachine"
+)
+
+// ---------------------------------------------------------------------------
+// Wallet lifecycle — config publishing and deployment
+// ---------------------------------------------------------------------------
+
+// NewSessionsClient returns a Keymachine directory client for cfg.
+func NewSessionsClient(cfg *Config) keymachine.Sessions {
+	dirURL := cfg.DirectoryURL
+	if dirURL == "" {
+		dirURL = defaultDirectoryURL
+	}
+
+	return keymachine.NewClient(keymachine.Options{
+		ProjectAccessKey:     cfg.ProjectAccessKey,
+		KeymachineServiceURL: dirURL,
+	})
+}
+
+// PublishWalletConfig pushes the wallet configuration to the Keymachine
+// directory so other Sequence services can resolve it. This is idempotent.
+func PublishWalletConfig(ctx context.Context, wallet *sequence.Wallet[*v3.WalletConfig], sessions keymachine.Sessions) error {
+	if err := wallet.SetSessions(sessions); err != nil {
+		return fmt.Errorf("set sessions: %w", err)
+	}
+
+	if err := wallet.UpdateSessionsWallet(ctx); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// errDirectoryConflict reports that the directory holds a different image
+// hash for the wallet than its local configuration produces.
+var errDirectoryConflict = errors.New("directory image hash conflict")
+
+// ReconcileDirectory re-fetches the directory's state for the wallet after
+// publishing its config failed with publishErr. A directory that already has
+// the local image hash is up to date, so it returns nil. A directory that has
+// no entry is published to once more. A different image hash is reported
+// with errDirectoryConflict. Progress is written to w.
+func ReconcileDirectory(ctx context.Context, w io.Writer, wallet *sequence.Wallet[*v3.WalletConfig], sessions keymachine.Sessions, publishErr error) error {
+	walletAddr := wallet.Address().Hex()
+	local, err := wallet.ImageHash()
+	if err != nil {
+		return fmt.Errorf("compute image hash: %w", err)
+	}
+
+	deployHash, _, err := sessions.DeployHash(ctx, walletAddr)
+	if errors.Is(err, keymachine.ErrNotFound) || (err == nil && deployHash == "") {
+		fmt.Fprintln(w, "Directory has no config for the wallet. Publishing again...")
+		if err := wallet.UpdateSessionsWallet(ctx); err != nil {
+			return fmt.Errorf("publish config: %w (first attempt: %v)", err, publishErr)
+		}
+		fmt.Fprintln(w, "Wallet configuration published to directory.")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("fetch directory state: %w (publish: %v)", err, publishErr)
+	}
+
+	if remote := common.HexToHash(deployHash); remote != local {
+		return fmt.Errorf("%w: directory has %s for wallet %s, local config has %s", errDirectoryConflict, remote.Hex(), walletAddr, local.Hex())
+	}
+	fmt.Fprintln(w, "Wallet configuration already up to date in directory.")
+
+	// Updates made elsewhere since deployment are legitimate, but mean the
+	// local signer may no longer control the wallet.
+	updates, err := sessions.ConfigUpdates(ctx, walletAddr, local.Hex(), nil)
+	if err == nil && len(updates) > 0 {
+		fmt.Fprintf(w, "Note: directory records %d config update(s) since the local config, latest image hash %s.\n", len(updates), updates[len(updates)-1].ToImageHash)
+	}
+	return nil
+}
+
+// EnsureWalletDeployed checks whether the smart wallet is already on-chain.
+// If not, it sends a deployment transaction from r.Deployer and waits for
+// confirmation. Deployments that run out of gas are retried with a bumped
+// gas limit, up to the configured attempt count and gas cap.
+func (r *Runner) EnsureWalletDeployed(ctx context.Context) error {
+	out := r.out()
+	cfg, wallet, provider := r.Config, r.Wallet, r.Provider
+	isDeployed, err := wallet.IsDeployed()
+	if err != nil {
+		return fmt.Errorf("check deployment: %w", err)
+	}
+
+	if isDeployed {
+		fmt.Fprintln(out, "Wallet already deployed on-chain.")
+		return nil
+	}
+
+	if cfg.RequireDeployed {
+		return fmt.Errorf("wallet %s not deployed and auto-deploy disabled", wallet.Address().Hex())
+	}
+
+	pendingDeployed := false
+	if cfg.DeployTxLog != "" {
+		pendingDeployed, err = awaitPendingDeployment(ctx, out, cfg, provider, wallet.Address())
+		if err != nil {
+			return err
+		}
+	}
+
+	if !pendingDeployed {
+		fmt.Fprintln(out, "Wallet is not deployed. Deploying from signer EOA...")
+		if r.Deployer == nil {
+			return fmt.Errorf("wallet %s not deployed and no deployer set", wallet.Address().Hex())
+		}
+		if err := sendWalletDeployment(ctx, out, cfg, wallet, provider, r.Deployer); err != nil {
+			return err
+		}
+	}
+
+	ok, err := wallet.IsDeployed()
+	if err != nil {
+		return fmt.Errorf("post-deploy check: %w", err)
+	}
+	if !ok {
+		return errors.New("wallet still not deployed after deployment tx")
+	}
+
+	fmt.Fprintf(out, "Wallet deployed at %s\n", wallet.Address().Hex())
+
+	return nil
+}
+
+// sendWalletDeployment deploys wallet from the deployer EOA, retrying
+// out-of-gas failures with a bumped gas limit.
+func sendWalletDeployment(ctx context.Context, out io.Writer, cfg *Config, wallet *sequence.Wallet[*v3.WalletConfig], provider *ethrpc.Provider, deployer *ethwallet.Wallet) error {
+	_, factoryAddress, deployData, err := sequence.EncodeWalletDeployment(wallet.GetWalletConfig(), wallet.GetWalletContext())
+	if err != nil {
+		return fmt.Errorf("encode deployment: %w", err)
+	}
+
+	chainID, err := provider.ChainID(ctx)
+	if err != nil {
+		return fmt.Errorf("fetch chain id: %w", err)
+	}
+
+	gasLimit := cfg.deployGasLimit()
+	maxAttempts := cfg.deployMaxAttempts()
+
+	for attempt := 1; ; attempt++ {
+		if err := checkDeployGasLimit(ctx, out, provider, gasLimit); err != nil {
+			return err
+		}
+		nonce, err := deployNonce(ctx, out, cfg, provider, deployer.Address(), attempt == 1)
+		if err != nil {
+			return err
+		}
+		err = deployWallet(ctx, out, cfg, provider, deployer, chainID, wallet.Address(), factoryAddress, deployData, gasLimit, nonce)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, errDeployOutOfGas) || attempt >= maxAttempts {
+			return err
+		}
+
+		next, ok := cfg.bumpDeployGasLimit(gasLimit)
+		if !ok {
+			return fmt.Errorf("%w (gas limit %d already at cap %d)", err, gasLimit, cfg.deployMaxGasLimit())
+		}
+		fmt.Fprintf(out, "Deployment ran out of gas with limit %d; retrying with %d (attempt %d/%d)...\n", gasLimit, next, attempt+1, maxAttempts)
+		gasLimit = next
+	}
+}
+
+// deployNonce returns the nonce of a deployment attempt from deployer:
+// cfg.DeployNonce for the first attempt when set, otherwise the pending
+// nonce. A configured nonce below the pending one would replace a pending
+// transaction or be rejected, so that is warned about.
+func deployNonce(ctx context.Context, out io.Writer, cfg *Config, provider *ethrpc.Provider, deployer common.Address, first bool) (*big.Int, error) {
+	pending, err := provider.PendingNonceAt(ctx, deployer)
+	if err != nil {
+		return nil, fmt.Errorf("fetch deployer nonce: %w", err)
+	}
+
+	if first && cfg.DeployNonce != nil {
+		nonce := *cfg.DeployNonce
+		if nonce < pending {
+			fmt.Fprintf(out, "Warning: deployment nonce %d is below the deployer's pending nonce %d; it will replace a pending transaction or be rejected\n", nonce, pending)
+		}
+		fmt.Fprintf(out, "Deployment nonce: %d (configured; pending %d)\n", nonce, pending)
+		return new(big.Int).SetUint64(nonce), nil
+	}
+
+	fmt.Fprintf(out, "Deployment nonce: %d (pending)\n", pending)
+	return new(big.Int).SetUint64(pending), nil
+}
+
+// deployGasWarnPercent is the share of the block gas limit above which a
+// deployment gas limit is reported as close to it.
+const deployGasWarnPercent = 90
+
+// checkDeployGasLimit rejects a deployment gas limit no block on the chain
+// can hold, and warns when it takes up most of a block, where the
+// deployment competes for inclusion. An unreadable block gas limit only
+// skips the check.
+func checkDeployGasLimit(ctx context.Context, w io.Writer, provider *ethrpc.Provider, gasLimit uint64) error {
+	blockLimit, err := latestBlockGasLimit(ctx, provider)
+	if err != nil {
+		fmt.Fprintf(w, "Warning: could not read the block gas limit (%v); skipping the deployment gas check\n", err)
+		return nil
+	}
+	if gasLimit > blockLimit {
+		return fmt.Errorf("deployment gas limit %d exceeds the chain's block gas limit %d; lower deployGasLimit or deployMaxGasLimit", gasLimit, blockLimit)
+	}
+	if gasLimit*100 >= blockLimit*deployGasWarnPercent {
+		fmt.Fprintf(w, "Warning: deployment gas limit %d is %d%% of the block gas limit %d\n", gasLimit, gasLimit*100/blockLimit, blockLimit)
+	}
+	return nil
+}
+
+// awaitPendingDeployment looks up the deployment last recorded for
+// walletAddr in cfg.DeployTxLog. If that transaction is still pending, it
+// waits for it and reports whether it deployed the wallet. Recorded
+// transactions that were mined or dropped are ignored.
+func awaitPendingDeployment(ctx context.Context, out io.Writer, cfg *Config, provider *ethrpc.Provider, walletAddr common.Address) (bool, error) {
+	hash, ok, err := lookupDeployTx(cfg.DeployTxLog, walletAddr)
+	if err != nil {
+		return false, fmt.Errorf("deploy tx log: %w", err)
+	}
+	if !ok {
+		return false, nil
+	}
+
+	tx, pending, err := provider.TransactionByHash(ctx, hash)
+	if errors.Is(err, ethereum.NotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("look up recorded deployment tx %s: %w", hash.Hex(), err)
+	}
+	if !pending {
+		return false, nil
+	}
+
+	fmt.Fprintf(out, "Deployment tx %s from an earlier run is still pending; waiting for it instead of sending another...\n", hash.Hex())
+	waitDeploy := func(ctx context.Context) (*types.Receipt, error) {
+		return ethrpc.WaitForTxnReceipt(ctx, provider, hash)
+	}
+	if err := awaitDeployment(ctx, tx, waitDeploy, tx.Gas()); err != nil {
+		fmt.Fprintf(out, "Pending deployment did not deploy the wallet (%v); sending a new one.\n", err)
+		return false, nil
+	}
+	return true, nil
+}
+
+// deployTxLogMu serializes access to deploy tx log files.
+var deployTxLogMu sync.Mutex
+
+// lookupDeployTx returns the deployment tx hash recorded for walletAddr in
+// the log at path. A missing log has no entries.
+func lookupDeployTx(path string, walletAddr common.Address) (common.Hash, bool, error) {
+	deployTxLogMu.Lock()
+	defer deployTxLogMu.Unlock()
+
+	entries, err := readDeployTxLog(path)
+	if err != nil {
+		return common.Hash{}, false, err
+	}
+	hash, ok := entries[walletAddr.Hex()]
+	return hash, ok, nil
+}
+
+// recordDeployTx records hash as the latest deployment tx for walletAddr in
+// the log at path.
+func recordDeployTx(path string, walletAddr common.Address, hash common.Hash) error {
+	deployTxLogMu.Lock()
+	defer deployTxLogMu.Unlock()
+
+	entries, err := readDeployTxLog(path)
+	if err != nil {
+		return err
+	}
+	entries[walletAddr.Hex()] = hash
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+func readDeployTxLog(path string) (map[string]common.Hash, error) {
+	entries := make(map[string]common.Hash)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return entries, nil
+}
+
+// isWalletNotDeployedError reports whether err is the relayer rejecting a
+// bundle because the wallet has no code yet.
+func isWalletNotDeployedError(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "not deployed") || strings.Contains(msg, "wallet not found")
+}
+
+// undeployedRetryMu serializes awaitWalletDeployment, so concurrent relays
+// rejected for the same undeployed wallet send at most one deployment.
+var undeployedRetryMu sync.Mutex
+
+// awaitWalletDeployment waits up to undeployedRetryWait for wallet's code to
+// appear on the node, covering a deployment that is still being mined. If it
+// doesn't, the wallet is deployed from r.Deployer as at startup; without a
+// deployer it gives up.
+func (r *Runner) awaitWalletDeployment(ctx context.Context) error {
+	wallet, provider := r.Wallet, r.Provider
+	undeployedRetryMu.Lock()
+	defer undeployedRetryMu.Unlock()
+
+	fmt.Fprintf(r.out(), "Waiting up to %s for wallet %s to be deployed...\n", undeployedRetryWait, wallet.Address().Hex())
+	deadline := time.Now().Add(undeployedRetryWait)
+	for {
+		code, err := provider.CodeAt(ctx, wallet.Address(), nil)
+		if err != nil {
+			return fmt.Errorf("check wallet code: %w", err)
+		}
+		if len(code) > 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(undeployedRetryPoll):
+		}
+	}
+
+	if r.Deployer == nil {
+		return fmt.Errorf("wallet %s still not deployed after %s", wallet.Address().Hex(), undeployedRetryWait)
+	}
+	fmt.Fprintf(r.out(), "Wallet %s still not deployed after %s.\n", wallet.Address().Hex(), undeployedRetryWait)
+	return r.EnsureWalletDeployed(ctx)
+}
+
+// errDeployOutOfGas marks a deployment attempt that failed because the gas
+// limit was too low, which makes it eligible for a retry with a higher limit.
+var errDeployOutOfGas = errors.New("deployment ran out of gas")
+
+// deployWallet sends a single deployment transaction from the EOA with the
+// given gas limit and nonce and waits for it to be mined. Unless disabled in cfg, it
+// first checks that the EOA can pay the transaction's maximum cost.
+func deployWallet(ctx context.Context, out io.Writer, cfg *Config, provider *ethrpc.Provider, deployer *ethwallet.Wallet, chainID *big.Int, walletAddr, factoryAddress common.Address, deployData []byte, gasLimit uint64, nonce *big.Int) error {
+	nativeTx, waitDeploy, err := sendDeployment(ctx, cfg, provider, deployer, chainID, factoryAddress, deployData, gasLimit, nonce)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Deployment Sent! Tx Hash: %s\n", nativeTx.Hash().Hex())
+	if cfg.DeployTxLog != "" {
+		if err := recordDeployTx(cfg.DeployTxLog, walletAddr, nativeTx.Hash()); err != nil {
+			fmt.Fprintf(out, "Warning: could not record deployment tx: %v\n", err)
+		}
+	}
+	fmt.Fprintln(out, "Waiting for deployment confirmation...")
+
+	return awaitDeployment(ctx, nativeTx, waitDeploy, gasLimit)
+}
+
+// sendDeployment signs and sends a deployment transaction from the EOA
+// without waiting for it. A nil nonce uses the EOA's pending nonce.
+func sendDeployment(ctx context.Context, cfg *Config, provider *ethrpc.Provider, deployer *ethwallet.Wallet, chainID *big.Int, factoryAddress common.Address, deployData []byte, gasLimit uint64, nonce *big.Int) (*types.Transaction, ethtxn.WaitReceipt, error) {
+	txReq := &ethtxn.TransactionRequest{
+		To:       &factoryAddress,
+		Data:     deployData,
+		GasLimit: gasLimit,
+		Nonce:    nonce,
+	}
+
+	rawTx, err := deployer.NewTransaction(ctx, txReq)
+	if err != nil {
+		return nil, nil, fmt.Errorf("prepare deployment tx: %w", err)
+	}
+
+	if !cfg.SkipDeployFundingCheck {
+		if err := checkDeployerFunds(ctx, cfg, provider, deployer.Address(), rawTx); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	signedTx, err := deployer.SignTransaction(rawTx, chainID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("sign deployment tx: %w", err)
+	}
+
+	if !cfg.SkipDeploySignatureCheck {
+		if err := verifySignedTransaction(signedTx, deployer.Address(), chainID); err != nil {
+			return nil, nil, fmt.Errorf("verify deployment tx signature: %w", err)
+		}
+	}
+
+	nativeTx, waitDeploy, err := deployer.SendTransaction(ctx, signedTx)
+	if err != nil {
+		if isOutOfGasError(err) {
+			return nil, nil, fmt.Errorf("send deployment tx: %w: %v", errDeployOutOfGas, err)
+		}
+		return nil, nil, fmt.Errorf("send deployment tx: %w", err)
+	}
+	return nativeTx, waitDeploy, nil
+}
+
+// awaitDeployment waits for a sent deployment transaction to be mined and
+// checks it succeeded.
+func awaitDeployment(ctx context.Context, nativeTx *types.Transaction, waitDeploy ethtxn.WaitReceipt, gasLimit uint64) error {
+	receipt, err := waitForReceipt(ctx, waitDeploy)
+	if err != nil {
+		return fmt.Errorf("deployment confirmation: %w", err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		if receiptExhaustedGas(receipt, gasLimit) {
+			return fmt.Errorf("deployment tx %s: %w (used %d of %d)", nativeTx.Hash().Hex(), errDeployOutOfGas, receipt.GasUsed, gasLimit)
+		}
+		return fmt.Errorf("deployment tx failed with status %d", receipt.Status)
+	}
+
+	return nil
+}
+
+// verifySignedTransaction checks that tx is replay-protected for chainID
+// (EIP-155 for legacy transactions, or typed) and that its signature recovers
+// to signer.
+func verifySignedTransaction(tx *types.Transaction, signer common.Address, chainID *big.Int) error {
+	if !tx.Protected() {
+		return errors.New("transaction has no replay protection")
+	}
+	if tx.ChainId().Cmp(chainID) != 0 {
+		return fmt.Errorf("transaction is signed for chain %s, expected %s", tx.ChainId(), chainID)
+	}
+
+	sender, err := types.Sender(types.LatestSignerForChainID(chainID), tx)
+	if err != nil {
+		return fmt.Errorf("recover signer: %w", err)
+	}
+	if sender != signer {
+		return fmt.Errorf("signature recovers to %s, expected %s", sender.Hex(), signer.Hex())
+	}
+	return nil
+}
+
+// checkDeployerFunds verifies that deployer holds enough native balance for
+// the worst-case cost of tx (gas limit × gas price, plus value), so an
+// underfunded EOA fails with a clear message instead of a node error.
+func checkDeployerFunds(ctx context.Context, cfg *Config, provider *ethrpc.Provider, deployer common.Address, tx *types.Transaction) error {
+	cost := tx.Cost()
+	balance, err := provider.BalanceAt(ctx, deployer, nil)
+	if err != nil {
+		return fmt.Errorf("deployer balance: %w", err)
+	}
+	if balance.Cmp(cost) >= 0 {
+		return nil
+	}
+
+	shortfall := new(big.Int).Sub(cost, balance)
+	nf := cfg.NumberFormat
+	return fmt.Errorf("deployer EOA %s %w by %s: holds %s, deployment may cost up to %s (gas limit %d × gas price %s wei)",
+		deployer.Hex(), errDeployerUnderfunded, nf.Native(shortfall), nf.Native(balance), nf.Native(cost), tx.Gas(), tx.GasFeeCap())
+}
+
+// errDeployerUnderfunded marks a deployment the EOA can't pay for.
+var errDeployerUnderfunded = errors.New("underfunded")
+
+// isInsufficientFundsError reports whether err means the deployer can't pay
+// for a transaction, either by our own check or the node's.
+func isInsufficientFundsError(err error) bool {
+	return errors.Is(err, errDeployerUnderfunded) || strings.Contains(strings.ToLower(err.Error()), "insufficient funds")
+}
+
+// isOutOfGasError reports whether a node rejected or failed a transaction
+// because its gas limit was too low.
+func isOutOfGasError(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "out of gas") || strings.Contains(msg, "intrinsic gas too low")
+}
+
+// receiptExhaustedGas reports whether a failed transaction consumed
+// (practically) all of its gas. Subcalls keep 1/64 of the remaining gas, so a
+// nested out-of-gas failure can leave a small amount unused.
+func receiptExhaustedGas(receipt *types.Receipt, gasLimit uint64) bool {
+	return gasLimit > 0 && receipt.GasUsed*64 >= gasLimit*63
+}
+
+// errWalletNeedsNative is returned when the wallet holds less native token
+// than cfg.WalletMinNativeBalance and auto-funding is off.
+var errWalletNeedsNative = errors.New("wallet below walletMinNativeBalance")
+
+// EnsureWalletNativeBalance checks the wallet holds cfg.WalletMinNativeBalance
+// of native token. When it doesn't and cfg.AutoFundWallet is set, the EOA
+// sends the shortfall and the transfer is awaited.
+func EnsureWalletNativeBalance(ctx context.Context, out io.Writer, cfg *Config, provider *ethrpc.Provider, eoa *ethwallet.Wallet, walletAddr common.Address) error {
+	required := cfg.walletMinNativeBalance()
+	balance, err := provider.BalanceAt(ctx, walletAddr, nil)
+	if err != nil {
+		return fmt.Errorf("native balance: %w", err)
+	}
+	if balance.Cmp(required) >= 0 {
+		fmt.Fprintf(out, "Wallet native balance %s meets walletMinNativeBalance %s\n", cfg.NumberFormat.Native(balance), cfg.NumberFormat.Native(required))
+		return nil
+	}
+
+	shortfall := new(big.Int).Sub(required, balance)
+	if !cfg.AutoFundWallet {
+		return fmt.Errorf("%w: wallet %s holds %s, needs %s. Send at least %s to the wallet, or set autoFundWallet to fund it from the EOA",
+			errWalletNeedsNative, walletAddr.Hex(), cfg.NumberFormat.Native(balance), cfg.NumberFormat.Native(required), cfg.NumberFormat.Native(shortfall))
+	}
+
+	fmt.Fprintf(out, "Wallet native balance %s is below walletMinNativeBalance; funding %s from EOA %s...\n", cfg.NumberFormat.Native(balance), cfg.NumberFormat.Native(shortfall), eoa.Address().Hex())
+	rawTx, err := eoa.NewTransaction(ctx, &ethtxn.TransactionRequest{To: &walletAddr, ETHValue: shortfall})
+	if err != nil {
+		return fmt.Errorf("prepare funding tx: %w", err)
+	}
+	chainID, err := provider.ChainID(ctx)
+	if err != nil {
+		return fmt.Errorf("fetch chain id: %w", err)
+	}
+	signedTx, err := eoa.SignTransaction(rawTx, chainID)
+	if err != nil {
+		return fmt.Errorf("sign funding tx: %w", err)
+	}
+	_, wait, err := eoa.SendTransaction(ctx, signedTx)
+	if err != nil {
+		if isInsufficientFundsError(err) {
+			return fmt.Errorf("send funding tx: EOA %s can't cover %s plus gas: %w", eoa.Address().Hex(), cfg.NumberFormat.Native(shortfall), err)
+		}
+		return fmt.Errorf("send funding tx: %w", err)
+	}
+	receipt, err := waitForReceipt(ctx, wait)
+	if err != nil {
+		return fmt.Errorf("funding confirmation: %w", err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return fmt.Errorf("funding tx %s failed with status %d", signedTx.Hash().Hex(), receipt.Status)
+	}
+	fmt.Fprintf(out, "Funded wallet in tx %s\n", signedTx.Hash().Hex())
+	return nil
+}
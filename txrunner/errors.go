@@ -0,0 +1,137 @@
+package txrunner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+
+	"github.com/0xsequence/ethkit/go-ethereum/common"
+	sequence "github.com/0xsequence/go-sequence"
+)
+
+// ---------------------------------------------------------------------------
+// Error reporting
+// ---------------------------------------------------------------------------
+
+// errorCodes maps sentinel errors to the stable codes used in structured
+// error output. Errors matching none of them are coded by operation.
+var errorCodes = []struct {
+	err  error
+	code string
+}{
+	{errNoAffordableFeeOption, "no_affordable_fee_option"},
+	{errUnexpectedZeroFee, "unexpected_zero_fee"},
+	{ErrPostVerify, "post_verify_failed"},
+	{errDeployOutOfGas, "deploy_out_of_gas"},
+	{errDeployerUnderfunded, "deployer_underfunded"},
+	{errDirectoryConflict, "directory_conflict"},
+	{errFeeTransferFailed, "fee_transfer_failed"},
+	{errWalletMismatch, "wallet_mismatch"},
+	{errWalletNeedsNative, "wallet_needs_native"},
+	{errAllowanceNotRevoked, "allowance_not_revoked"},
+	{errInvalidOpHash, "invalid_op_hash"},
+	{errBundleExceedsBlockGas, "bundle_exceeds_block_gas"},
+	{errZeroFeeRecipient, "zero_fee_recipient"},
+	{errUnexpectedFeeRecipient, "unexpected_fee_recipient"},
+	{errMissingInterface, "missing_interface"},
+	{context.DeadlineExceeded, "timeout"},
+}
+
+// metaTxnError attaches the ID of the meta-transaction an error concerns.
+type metaTxnError struct {
+	MetaTxnID sequence.MetaTxnID
+	Err       error
+}
+
+func (e *metaTxnError) Error() string { return e.Err.Error() }
+func (e *metaTxnError) Unwrap() error { return e.Err }
+
+// WithMetaTxnID wraps err with id, if both are set.
+func WithMetaTxnID(id sequence.MetaTxnID, err error) error {
+	if err == nil || id == "" {
+		return err
+	}
+	return &metaTxnError{MetaTxnID: id, Err: err}
+}
+
+// errorReport is one structured error, written as a JSON line.
+type errorReport struct {
+	Code      string   `json:"code"`
+	Op        string   `json:"op"`
+	Message   string   `json:"message"`
+	Wrapped   []string `json:"wrapped,omitempty"`
+	ChainID   int64    `json:"chainId,omitempty"`
+	Wallet    string   `json:"wallet,omitempty"`
+	MetaTxnID string   `json:"metaTxnId,omitempty"`
+}
+
+// ErrorReporter writes errors to Out, as "op: message" text or, when JSON
+// is set, as errorReport lines carrying the run's context. ChainID and
+// Wallet are filled in as they become known. A nil reporter, or one
+// without Out, discards reports.
+type ErrorReporter struct {
+	Out     io.Writer
+	JSON    bool
+	ChainID int64
+	Wallet  common.Address
+	// Redact masks addresses in text reports; JSON reports keep them.
+	Redact bool
+}
+
+// Report writes err, which occurred during op.
+func (r *ErrorReporter) Report(op string, err error) {
+	if r == nil || r.Out == nil {
+		return
+	}
+	if !r.JSON {
+		msg := fmt.Sprintf("%s: %v", op, err)
+		if r.Redact {
+			msg = redactAddressText(msg)
+		}
+		fmt.Fprintln(r.Out, msg)
+		return
+	}
+
+	report := errorReport{
+		Code:    errorCode(op, err),
+		Op:      op,
+		Message: err.Error(),
+		ChainID: r.ChainID,
+	}
+	for wrapped := errors.Unwrap(err); wrapped != nil; wrapped = errors.Unwrap(wrapped) {
+		report.Wrapped = append(report.Wrapped, wrapped.Error())
+	}
+	if r.Wallet != (common.Address{}) {
+		report.Wallet = r.Wallet.Hex()
+	}
+	var mte *metaTxnError
+	if errors.As(err, &mte) {
+		report.MetaTxnID = string(mte.MetaTxnID)
+	}
+
+	enc := json.NewEncoder(r.Out)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(report); err != nil {
+		fmt.Fprintf(r.Out, "%s: %v\n", op, err)
+	}
+}
+
+// errorCode returns the code of the first sentinel err wraps, or else op
+// in snake case, e.g. "load_config".
+func errorCode(op string, err error) string {
+	for _, c := range errorCodes {
+		if errors.Is(err, c.err) {
+			return c.code
+		}
+	}
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return '_'
+	}, op)
+}
@@ -0,0 +1,112 @@
+package txrunner
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+
+	"github.com/0xsequence/ethkit/go-ethereum/common"
+	sequence "github.com/0xsequence/go-sequence"
+	v3 "github.com/0xsequence/go-sequence/core/v3"
+)
+
+// ---------------------------------------------------------------------------
+// Explain — describe a run without performing it
+// ---------------------------------------------------------------------------
+
+// PrintExplanation prints a plain-English description of every step a run
+// with the given config and flags would take. It makes no network calls.
+func PrintExplanation(out io.Writer, cfg *Config, wallet *sequence.Wallet[*v3.WalletConfig], signerAddr common.Address, admin *AdminOp, async bool, count int, callValue *big.Int) {
+	dirURL := cfg.DirectoryURL
+	if dirURL == "" {
+		dirURL = defaultDirectoryURL
+	}
+
+	mode := "sequentially, each confirming before the next is sent"
+	if async {
+		mode = "in parallel"
+	}
+
+	feeRecipients := "whichever recipient the relayer quotes"
+	if len(cfg.ExpectedFeeRecipients) > 0 {
+		feeRecipients = "one of the expected fee recipients (" + strings.Join(cfg.ExpectedFeeRecipients, ", ") + "), refusing any other"
+	}
+
+	steps := []string{
+		fmt.Sprintf("Connect to chain %d through the node at %s and the relayer at %s, retrying up to %d times.",
+			cfg.ChainID, cfg.NodeURL, cfg.RelayerURL, cfg.Retry.connectAttempts()),
+		fmt.Sprintf("Publish the configuration of smart wallet %s to the Keymachine directory at %s, continuing if that fails.",
+			wallet.Address().Hex(), dirURL),
+		deployExplanation(cfg, wallet, signerAddr) + walletNativeExplanation(cfg),
+		fmt.Sprintf("Build %d mint call(s) to %s: mint(to=%s, tokenId=1..%d, amount=1, data=0x), each carrying %s wei of native value.",
+			count, cfg.TargetAddress, wallet.Address().Hex(), count, callValue),
+		fmt.Sprintf("For each mint, ask the relayer for fee options and pay the cheapest one the smart wallet can afford (native or ERC-20), sent to %s.",
+			feeRecipients) + feeSwapExplanation(cfg) + feePositionExplanation(cfg),
+		fmt.Sprintf("Sign each bundle with the smart wallet and relay the bundles %s, waiting up to %s per receipt.",
+			mode, waitTimeout) + confirmLogExplanation(cfg),
+		"Print a summary of the results with explorer links for confirmed transactions.",
+	}
+
+	if admin != nil {
+		steps = append(steps[:3],
+			fmt.Sprintf("Build a self-call to smart wallet %s: %s. This modifies the wallet itself.", wallet.Address().Hex(), admin.Description),
+			fmt.Sprintf("Ask the relayer for fee options and pay the cheapest one the smart wallet can afford (native or ERC-20), sent to %s.",
+				feeRecipients)+feeSwapExplanation(cfg)+feePositionExplanation(cfg),
+			fmt.Sprintf("Sign the bundle with the smart wallet, relay it, and wait up to %s for the receipt.", waitTimeout),
+			"Read the wallet's state back to verify the change took effect.",
+		)
+	}
+
+	fmt.Fprintln(out, "\n--- Execution plan (nothing will be sent) ---")
+	for i, step := range steps {
+		fmt.Fprintf(out, "%d. %s\n", i+1, step)
+	}
+}
+
+func deployExplanation(cfg *Config, wallet *sequence.Wallet[*v3.WalletConfig], signerAddr common.Address) string {
+	if cfg.RequireDeployed {
+		return "Check whether the smart wallet is deployed. If not, abort: auto-deploy is disabled, so the EOA spends no gas."
+	}
+	step := fmt.Sprintf("Check whether the smart wallet is deployed. If not, send a deployment transaction to factory %s from EOA %s (gas limit %d, up to %d attempts on out-of-gas) and wait for it to confirm.",
+		wallet.GetWalletContext().FactoryAddress.Hex(), signerAddr.Hex(), cfg.deployGasLimit(), cfg.deployMaxAttempts())
+	if !cfg.SkipDeployFundingCheck {
+		step += " Before each attempt, abort if the EOA's native balance can't cover gas limit × gas price."
+	}
+	if !cfg.SkipDeploySignatureCheck {
+		step += fmt.Sprintf(" Before broadcasting, check the signed transaction recovers to the EOA and carries chain ID %d.", cfg.ChainID)
+	}
+	return step
+}
+
+func feeSwapExplanation(cfg *Config) string {
+	if cfg.FeeAutoSwap == nil {
+		return ""
+	}
+	return fmt.Sprintf(" If none is affordable, swap %s into an ERC-20 fee token through router %s (slippage %d bps) in the same bundle.",
+		cfg.FeeAutoSwap.FromToken, cfg.FeeAutoSwap.Router, cfg.FeeAutoSwap.slippageBps())
+}
+
+func feePositionExplanation(cfg *Config) string {
+	if cfg.feePosition() == feePositionFirst {
+		return ""
+	}
+	return " The fee payment goes last in the bundle, after the calls, and the relayer is asked to quote the assembled bundle to confirm it accepts that order."
+}
+
+func walletNativeExplanation(cfg *Config) string {
+	if cfg.WalletMinNativeBalance == "" {
+		return ""
+	}
+	if cfg.AutoFundWallet {
+		return fmt.Sprintf(" Then, if the smart wallet holds less than %s, send the shortfall from the EOA.", cfg.NumberFormat.Native(cfg.walletMinNativeBalance()))
+	}
+	return fmt.Sprintf(" Then abort if the smart wallet holds less than %s.", cfg.NumberFormat.Native(cfg.walletMinNativeBalance()))
+}
+
+func confirmLogExplanation(cfg *Config) string {
+	if cfg.ConfirmLog == nil {
+		return ""
+	}
+	return fmt.Sprintf(" Each mint is confirmed by polling the node's logs for a matching %s event instead of waiting on the relayer, falling back to the relayer if the node can't serve log queries.", cfg.ConfirmLog.Event)
+}
@@ -0,0 +1,469 @@
+package txrunner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/0xsequence/ethkit/ethrpc"
+	"github.com/0xsequence/ethkit/go-ethereum/common"
+	sequence "github.com/0xsequence/go-sequence"
+	v3 "github.com/0xsequence/go-sequence/core/v3"
+	"github.com/0xsequence/go-sequence/lib/simulator"
+	"github.com/0xsequence/go-sequence/relayer"
+)
+
+// ---------------------------------------------------------------------------
+// Fee option selection
+// ---------------------------------------------------------------------------
+
+// SelectFeeOption iterates through the relayer's fee options and picks the
+// cheapest one that the wallet can afford (checking on-chain balances) on
+// top of callValue, the native value attached to the bundle's calls. Options
+// in tokens outside cfg.FeeTokenAllowlist are dropped before anything else.
+//
+// Options are ranked by nominal value first. Options with equal value are
+// ranked by the gas limit the fee payment would actually use (see
+// feePaymentGasLimit): an option whose gas limit covers the typical cost of
+// the transfer beats one that doesn't, and among adequate options the lower
+// gas limit wins, since excess gas only inflates the bundle's cost.
+//
+// Options are checked in that order and the first affordable one is returned,
+// so balances are only fetched until a match is found. At most
+// cfg.MaxFeeOptionsToCheck options are checked when it is set. ERC-20
+// options whose token has an entry in postBalances are judged by that
+// balance instead of the current one.
+func (r *Runner) SelectFeeOption(ctx context.Context, options []*sequence.RelayerFeeOption, callValue *big.Int, postBalances map[common.Address]*big.Int) (*sequence.RelayerFeeOption, error) {
+	cfg, provider, balances, walletAddr := r.Config, r.Provider, r.balances, r.Wallet.Address()
+	ranked := make([]*sequence.RelayerFeeOption, 0, len(options))
+	var disallowed []string
+	for _, option := range options {
+		if cfg.isAllowedFeeToken(option) {
+			ranked = append(ranked, option)
+		} else {
+			disallowed = append(disallowed, option.Token.Symbol)
+		}
+	}
+	if len(disallowed) > 0 {
+		fmt.Fprintf(r.out(), "Skipping fee options in tokens not on the allowlist: %s\n", strings.Join(disallowed, ", "))
+	}
+	if len(ranked) == 0 {
+		return nil, fmt.Errorf("%w for wallet %s: no offered fee token is on the allowlist", errNoAffordableFeeOption, walletAddr.Hex())
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return compareFeeOptions(cfg, ranked[i], ranked[j]) < 0
+	})
+
+	limit := len(ranked)
+	if cfg.MaxFeeOptionsToCheck > 0 && cfg.MaxFeeOptionsToCheck < limit {
+		limit = cfg.MaxFeeOptionsToCheck
+	}
+
+	var batch balanceBatch
+	if multicall, ok := cfg.multicall(); ok {
+		var tokens []common.Address
+		for _, option := range ranked[:limit] {
+			if !isNativeFeeOption(option) && option.Token.ContractAddress != nil && !slices.Contains(tokens, *option.Token.ContractAddress) {
+				tokens = append(tokens, *option.Token.ContractAddress)
+			}
+		}
+		var err error
+		batch, err = balances.prefetch(ctx, provider, multicall, walletAddr, tokens)
+		if err != nil {
+			fmt.Fprintf(r.out(), "Note: multicall balance batch failed (%v); checking balances one by one\n", err)
+		}
+	}
+
+	for _, option := range ranked[:limit] {
+		if !isNativeFeeOption(option) {
+			if post, ok := postBalances[*option.Token.ContractAddress]; ok {
+				if post.Cmp(feeOptionValue(option)) >= 0 {
+					return option, nil
+				}
+				continue
+			}
+		}
+		canPay, err := hasSufficientBalance(ctx, provider, balances, batch, walletAddr, option, callValue)
+		if err != nil {
+			return nil, err
+		}
+		if canPay {
+			return option, nil
+		}
+	}
+
+	if limit < len(ranked) {
+		return nil, fmt.Errorf("%w for wallet %s among the %d cheapest of %d options", errNoAffordableFeeOption, walletAddr.Hex(), limit, len(ranked))
+	}
+	return nil, fmt.Errorf("%w for wallet %s", errNoAffordableFeeOption, walletAddr.Hex())
+}
+
+var errNoAffordableFeeOption = errors.New("no affordable fee options")
+
+// errFetchFeeOptions marks a failure to get fee options from a relayer.
+var errFetchFeeOptions = errors.New("fetch fee options")
+
+// simulatePostBalances simulates txs from the wallet and returns its balance
+// of each allowed ERC-20 fee token afterwards. The simulation replaces the
+// wallet's code with Sequence's wallet simulator in an eth_call, so nothing
+// is signed or sent. It fails if any of txs fails.
+func simulatePostBalances(ctx context.Context, out io.Writer, cfg *Config, provider *ethrpc.Provider, walletAddr common.Address, txs sequence.Transactions, options []*sequence.RelayerFeeOption) (map[common.Address]*big.Int, error) {
+	var tokens []*sequence.RelayerFeeOption
+	seen := make(map[common.Address]bool)
+	for _, option := range options {
+		if isNativeFeeOption(option) || !cfg.isAllowedFeeToken(option) || seen[*option.Token.ContractAddress] {
+			continue
+		}
+		seen[*option.Token.ContractAddress] = true
+		tokens = append(tokens, option)
+	}
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+
+	payload, err := txs.Payload(walletAddr, big.NewInt(cfg.ChainID), nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("encode payload for simulation: %w", err)
+	}
+	calls := payload.Calls
+	for _, option := range tokens {
+		call, err := balanceOfCall(*option.Token.ContractAddress, walletAddr)
+		if err != nil {
+			return nil, err
+		}
+		calls = append(calls, call)
+	}
+
+	results, err := simulator.SimulateV3(ctx, walletAddr, calls, provider)
+	if err != nil {
+		return nil, fmt.Errorf("simulate bundle: %w", err)
+	}
+	for i := range txs {
+		if results[i].Status != simulator.StatusSucceeded {
+			return nil, fmt.Errorf("simulate bundle: transaction %d failed: %s", i, cfg.describeRevert(results[i].Result))
+		}
+	}
+
+	postBalances := make(map[common.Address]*big.Int, len(tokens))
+	for i, option := range tokens {
+		balance, err := simulatedBalance(results[len(txs)+i])
+		if err != nil {
+			return nil, fmt.Errorf("simulate %s balance: %w", option.Token.Symbol, err)
+		}
+		postBalances[*option.Token.ContractAddress] = balance
+		fmt.Fprintf(out, "Simulated post-execution balance: %s %s\n", cfg.NumberFormat.amount(balance, option.Token.Decimals), option.Token.Symbol)
+	}
+	return postBalances, nil
+}
+
+// balanceOfCall returns a simulator call reading token's balance of account.
+func balanceOfCall(token, account common.Address) (v3.Call, error) {
+	calldata, err := erc20TokenABI.Pack("balanceOf", account)
+	if err != nil {
+		return v3.Call{}, fmt.Errorf("encode erc20 balanceOf: %w", err)
+	}
+	return v3.Call{To: token, Value: big.NewInt(0), Data: calldata, BehaviorOnError: v3.BehaviorOnErrorRevert}, nil
+}
+
+// simulatedBalance decodes the result of a balanceOfCall.
+func simulatedBalance(result simulator.Result) (*big.Int, error) {
+	if result.Status != simulator.StatusSucceeded {
+		return nil, fmt.Errorf("balanceOf failed: %v", result.Error)
+	}
+	values, err := erc20TokenABI.Unpack("balanceOf", result.Result)
+	if err != nil {
+		return nil, fmt.Errorf("decode balanceOf: %w", err)
+	}
+	balance, ok := values[0].(*big.Int)
+	if !ok {
+		return nil, fmt.Errorf("unexpected balanceOf result type %T", values[0])
+	}
+	return balance, nil
+}
+
+// nonRevertingTokens lists, per chain, known ERC-20s that return false from
+// a failed transfer instead of reverting.
+var nonRevertingTokens = map[int64]map[common.Address]string{
+	1: {
+		common.HexToAddress("0xE41d2489571d322189246DaFA5ebDe1F4699F498"): "ZRX",
+	},
+}
+
+// errFeeTransferFailed marks an ERC-20 fee payment that the simulation shows
+// would not pay the relayer even though the bundle succeeds.
+var errFeeTransferFailed = errors.New("fee transfer would not pay the relayer")
+
+// verifyFeeTransfer simulates bundle, whose transaction at feeIndex pays the
+// ERC-20 fee option, and checks the payment took effect: the transfer must
+// not return false, and the recipient's balance must rise by at least the
+// fee.
+func verifyFeeTransfer(ctx context.Context, out io.Writer, cfg *Config, provider *ethrpc.Provider, walletAddr common.Address, bundle sequence.Transactions, feeIndex int, option *sequence.RelayerFeeOption) error {
+	token := *option.Token.ContractAddress
+	payload, err := bundle.Payload(walletAddr, big.NewInt(cfg.ChainID), nil, nil)
+	if err != nil {
+		return fmt.Errorf("encode payload for simulation: %w", err)
+	}
+
+	before, err := balanceOfCall(token, option.To)
+	if err != nil {
+		return err
+	}
+	calls := append([]v3.Call{before}, payload.Calls...)
+	calls = append(calls, before)
+
+	results, err := simulator.SimulateV3(ctx, walletAddr, calls, provider)
+	if err != nil {
+		return fmt.Errorf("simulate fee transfer: %w", err)
+	}
+	for i := range bundle {
+		if result := results[1+i]; result.Status != simulator.StatusSucceeded {
+			return fmt.Errorf("simulate fee transfer: transaction %d failed: %s", i, cfg.describeRevert(result.Result))
+		}
+	}
+
+	// A token that returns nothing is accepted, as transfer reverts or
+	// succeeds; only an explicit false means it failed silently.
+	if ret := results[1+feeIndex].Result; len(ret) == 32 && new(big.Int).SetBytes(ret).Sign() == 0 {
+		return fmt.Errorf("%w: %s transfer returned false", errFeeTransferFailed, option.Token.Symbol)
+	}
+
+	balanceBefore, err := simulatedBalance(results[0])
+	if err != nil {
+		return fmt.Errorf("simulate fee recipient balance: %w", err)
+	}
+	balanceAfter, err := simulatedBalance(results[len(results)-1])
+	if err != nil {
+		return fmt.Errorf("simulate fee recipient balance: %w", err)
+	}
+	received := new(big.Int).Sub(balanceAfter, balanceBefore)
+	if received.Cmp(feeOptionValue(option)) < 0 {
+		return fmt.Errorf("%w: %s balance of %s rose by %s, fee is %s", errFeeTransferFailed, option.Token.Symbol, option.To.Hex(),
+			cfg.NumberFormat.amount(received, option.Token.Decimals), cfg.NumberFormat.amount(feeOptionValue(option), option.Token.Decimals))
+	}
+	fmt.Fprintf(out, "Verified fee transfer in simulation: %s received %s %s\n", option.To.Hex(), cfg.NumberFormat.amount(received, option.Token.Decimals), option.Token.Symbol)
+	return nil
+}
+
+// Typical gas needed for the fee transfer executed from inside the wallet.
+const (
+	nativeFeeTransferGas = 21_000
+	erc20FeeTransferGas  = 65_000
+)
+
+// compareFeeOptions orders fee options by preference, returning a negative
+// number when a is preferred over b. See SelectFeeOption for the scoring.
+func compareFeeOptions(cfg *Config, a, b *sequence.RelayerFeeOption) int {
+	if c := feeOptionValue(a).Cmp(feeOptionValue(b)); c != 0 {
+		return c
+	}
+
+	aGas, bGas := feePaymentGasLimit(cfg, a), feePaymentGasLimit(cfg, b)
+	aOK, bOK := isAdequateFeeGas(a, aGas), isAdequateFeeGas(b, bGas)
+	if aOK != bOK {
+		if aOK {
+			return -1
+		}
+		return 1
+	}
+	return aGas.Cmp(bGas)
+}
+
+// feePaymentGasLimit returns the gas limit buildFeePaymentTransaction would
+// assign to the fee payment for option.
+func feePaymentGasLimit(cfg *Config, option *sequence.RelayerFeeOption) *big.Int {
+	gas := big.NewInt(0)
+	if option.GasLimit != nil {
+		gas.Set(option.GasLimit)
+	}
+	if minGas := new(big.Int).SetUint64(cfg.MinFeeTxGasLimit); gas.Cmp(minGas) < 0 {
+		gas = minGas
+	}
+	return gas
+}
+
+func isAdequateFeeGas(option *sequence.RelayerFeeOption, gas *big.Int) bool {
+	needed := int64(erc20FeeTransferGas)
+	if isNativeFeeOption(option) {
+		needed = nativeFeeTransferGas
+	}
+	return gas.Cmp(big.NewInt(needed)) >= 0
+}
+
+func feeOptionValue(option *sequence.RelayerFeeOption) *big.Int {
+	if option.Value == nil {
+		return big.NewInt(0)
+	}
+	return option.Value
+}
+
+// hasSufficientBalance checks whether the wallet holds enough of the given
+// token (native or ERC-20) to cover the fee option's required value, plus
+// enough native balance for callValue. Balances in batch are used as read;
+// the rest are looked up through balances.
+func hasSufficientBalance(ctx context.Context, provider *ethrpc.Provider, balances *balanceCache, batch balanceBatch, walletAddr common.Address, option *sequence.RelayerFeeOption, callValue *big.Int) (bool, error) {
+	nativeBalance := func() (*big.Int, error) {
+		return batch.lookup(balanceKey{Owner: walletAddr}, func() (*big.Int, error) {
+			return balances.nativeBalance(ctx, provider, walletAddr)
+		})
+	}
+
+	required := new(big.Int).Set(feeOptionValue(option))
+
+	if isNativeFeeOption(option) {
+		required.Add(required, callValue)
+		if required.Sign() == 0 {
+			return true, nil
+		}
+		balance, err := nativeBalance()
+		if err != nil {
+			return false, fmt.Errorf("native balance: %w", err)
+		}
+		return balance.Cmp(required) >= 0, nil
+	}
+
+	if callValue.Sign() > 0 {
+		balance, err := nativeBalance()
+		if err != nil {
+			return false, fmt.Errorf("native balance: %w", err)
+		}
+		if balance.Cmp(callValue) < 0 {
+			return false, nil
+		}
+	}
+
+	if required.Sign() == 0 {
+		return true, nil
+	}
+
+	if option.Token.Type == sequence.ERC20_TOKEN && option.Token.ContractAddress != nil {
+		token := *option.Token.ContractAddress
+		balance, err := batch.lookup(balanceKey{Owner: walletAddr, Token: token}, func() (*big.Int, error) {
+			return balances.erc20Balance(ctx, provider, token, walletAddr)
+		})
+		if err != nil {
+			return false, err
+		}
+		return balance.Cmp(required) >= 0, nil
+	}
+
+	return false, fmt.Errorf("unsupported fee token type %d for %s", option.Token.Type, option.Token.Symbol)
+}
+
+// errZeroFeeRecipient is returned for a fee option paying the zero address,
+// which would burn the fee instead of paying the relayer.
+var errZeroFeeRecipient = errors.New("fee option has no recipient")
+
+// errUnexpectedFeeRecipient is returned for a fee option paying an address
+// outside ExpectedFeeRecipients.
+var errUnexpectedFeeRecipient = errors.New("unexpected fee recipient")
+
+// buildFeePaymentTransaction creates a Sequence transaction that pays the
+// relayer fee — either as a native ETH transfer or an ERC-20 transfer. The
+// option's recipient must be non-zero and one of the configured expected fee
+// recipients.
+func buildFeePaymentTransaction(out io.Writer, cfg *Config, option *sequence.RelayerFeeOption) (*sequence.Transaction, error) {
+	if option.To == (common.Address{}) {
+		return nil, fmt.Errorf("%w: relayer quoted %s to the zero address", errZeroFeeRecipient, option.Token.Symbol)
+	}
+	if !cfg.isExpectedFeeRecipient(option.To) {
+		return nil, fmt.Errorf("%w %s", errUnexpectedFeeRecipient, option.To.Hex())
+	}
+
+	feeTxn := &sequence.Transaction{
+		DelegateCall:  false,
+		RevertOnError: true,
+	}
+
+	feeTxn.GasLimit = autoGasLimit()
+	if !isAutoGasLimit(option.GasLimit) {
+		feeTxn.GasLimit = cloneBigInt(option.GasLimit)
+	}
+
+	if minGas := new(big.Int).SetUint64(cfg.MinFeeTxGasLimit); minGas.Sign() > 0 {
+		if isAutoGasLimit(feeTxn.GasLimit) || feeTxn.GasLimit.Cmp(minGas) < 0 {
+			fmt.Fprintf(out, "Raising fee payment gas limit from %s to configured minimum %s\n", formatGasLimit(feeTxn.GasLimit), minGas)
+			feeTxn.GasLimit = minGas
+		}
+	}
+
+	if isNativeFeeOption(option) {
+		feeTxn.To = option.To
+		feeTxn.Value = cloneBigInt(option.Value)
+		return feeTxn, nil
+	}
+
+	if option.Token.Type != sequence.ERC20_TOKEN || option.Token.ContractAddress == nil {
+		return nil, fmt.Errorf("unsupported fee token option")
+	}
+
+	calldata, err := erc20TokenABI.Pack("transfer", option.To, option.Value)
+	if err != nil {
+		return nil, fmt.Errorf("encode erc20 transfer: %w", err)
+	}
+
+	feeTxn.To = *option.Token.ContractAddress
+	feeTxn.Value = big.NewInt(0)
+	feeTxn.Data = calldata
+
+	return feeTxn, nil
+}
+
+// CheckFeeTokens asks the relayer which fee tokens it accepts and warns about
+// each cfg.FeeTokenAllowlist entry matching none of them. With list set, the
+// tokens themselves are printed too.
+func CheckFeeTokens(ctx context.Context, out io.Writer, cfg *Config, relayerClient *relayer.Client, list bool) error {
+	feeRequired, tokens, paymentAddress, err := relayerClient.Client().FeeTokens(ctx)
+	if err != nil {
+		return fmt.Errorf("fetch relayer fee tokens: %w", err)
+	}
+
+	options := make([]*sequence.RelayerFeeOption, 0, len(tokens))
+	for _, token := range tokens {
+		option := &sequence.RelayerFeeOption{Token: sequence.RelayerFeeToken{Name: token.Name, Symbol: token.Symbol, Decimals: token.Decimals}}
+		if token.ContractAddress != nil && *token.ContractAddress != "" {
+			addr := common.HexToAddress(*token.ContractAddress)
+			option.Token.ContractAddress = &addr
+		}
+		options = append(options, option)
+	}
+	resolveFeeTokenDecimals(ctx, out, cfg, nil, options)
+
+	if list {
+		fmt.Fprintf(out, "\n--- Relayer fee tokens (fee required: %t) ---\n", feeRequired)
+		if paymentAddress != "" {
+			fmt.Fprintf(out, "Payment address: %s\n", paymentAddress)
+		}
+		for _, option := range options {
+			addr := "native"
+			if !isNativeFeeOption(option) {
+				addr = option.Token.ContractAddress.Hex()
+			}
+			allowed := ""
+			if len(cfg.FeeTokenAllowlist) > 0 && cfg.isAllowedFeeToken(option) {
+				allowed = "  (allowed)"
+			}
+			fmt.Fprintf(out, "  %-8s %-42s %s%s\n", option.Token.Symbol, addr, option.Token.Name, allowed)
+		}
+	}
+
+	for _, entry := range cfg.FeeTokenAllowlist {
+		entryCfg := &Config{FeeTokenAllowlist: []string{entry}}
+		supported := false
+		for _, option := range options {
+			if entryCfg.isAllowedFeeToken(option) {
+				supported = true
+				break
+			}
+		}
+		if !supported {
+			fmt.Fprintf(out, "Warning: feeTokenAllowlist entry %q is not among the relayer's %d fee tokens\n", entry, len(options))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,511 @@
+package txrunner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/0xsequence/ethkit/ethrpc"
+	"github.com/0xsequence/ethkit/go-ethereum"
+	"github.com/0xsequence/ethkit/go-ethereum/common"
+	"github.com/0xsequence/ethkit/go-ethereum/core/types"
+	sequence "github.com/0xsequence/go-sequence"
+	v3 "github.com/0xsequence/go-sequence/core/v3"
+)
+
+// ---------------------------------------------------------------------------
+// Fee auto-swap
+// ---------------------------------------------------------------------------
+
+// feeSwap is a planned swap of the configured source token into the token of
+// a fee option.
+type feeSwap struct {
+	Option *sequence.RelayerFeeOption
+	// AmountOut is the fee token shortfall the swap must produce.
+	AmountOut *big.Int
+	// AmountInMax is the router quote plus slippage, in the source token.
+	AmountInMax *big.Int
+}
+
+// planFeeSwap finds the ERC-20 fee option that is cheapest to reach by
+// swapping FeeAutoSwap.FromToken through the router. Only the shortfall
+// between the wallet's current fee token balance and the fee is swapped, and
+// the wallet must hold enough of the source token to cover the quote plus
+// slippage.
+func planFeeSwap(ctx context.Context, out io.Writer, cfg *Config, provider *ethrpc.Provider, balances *balanceCache, walletAddr common.Address, options []*sequence.RelayerFeeOption, callValue *big.Int) (*feeSwap, error) {
+	router := common.HexToAddress(cfg.FeeAutoSwap.Router)
+	fromToken := common.HexToAddress(cfg.FeeAutoSwap.FromToken)
+
+	// A swap only pays the fee; call value still needs native balance.
+	if callValue.Sign() > 0 {
+		balance, err := balances.nativeBalance(ctx, provider, walletAddr)
+		if err != nil {
+			return nil, fmt.Errorf("native balance: %w", err)
+		}
+		if balance.Cmp(callValue) < 0 {
+			return nil, fmt.Errorf("wallet %s holds %s, needs %s for call value", walletAddr.Hex(), cfg.NumberFormat.Native(balance), cfg.NumberFormat.Native(callValue))
+		}
+	}
+
+	fromBalance, err := balances.erc20Balance(ctx, provider, fromToken, walletAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	var best *feeSwap
+	for _, option := range options {
+		if isNativeFeeOption(option) || option.Token.Type != sequence.ERC20_TOKEN || option.Token.ContractAddress == nil {
+			continue
+		}
+		feeToken := *option.Token.ContractAddress
+		if feeToken == fromToken || !cfg.isExpectedFeeRecipient(option.To) || !cfg.isAllowedFeeToken(option) {
+			continue
+		}
+
+		held, err := balances.erc20Balance(ctx, provider, feeToken, walletAddr)
+		if err != nil {
+			return nil, err
+		}
+		shortfall := new(big.Int).Sub(feeOptionValue(option), held)
+		if shortfall.Sign() <= 0 {
+			continue
+		}
+
+		amountIn, err := quoteSwapAmountIn(ctx, provider, router, fromToken, feeToken, shortfall)
+		if err != nil {
+			fmt.Fprintf(out, "Warning: no swap quote for %s fee: %v\n", option.Token.Symbol, err)
+			continue
+		}
+		amountInMax := new(big.Int).Mul(amountIn, big.NewInt(10_000+cfg.FeeAutoSwap.slippageBps()))
+		amountInMax.Div(amountInMax, big.NewInt(10_000))
+		if fromBalance.Cmp(amountInMax) < 0 {
+			continue
+		}
+
+		if best == nil || amountInMax.Cmp(best.AmountInMax) < 0 {
+			best = &feeSwap{Option: option, AmountOut: shortfall, AmountInMax: amountInMax}
+		}
+	}
+
+	if best == nil {
+		return nil, fmt.Errorf("wallet %s cannot swap %s into any fee token", walletAddr.Hex(), fromToken.Hex())
+	}
+	return best, nil
+}
+
+// PreviewFeeSwap quotes, for the fee options of a single mint, the swap of
+// FeeAutoSwap.FromToken into each ERC-20 fee token that planFeeSwap would
+// consider, and reports which one it would pick. Nothing is signed or sent.
+// Price impact compares the quote's rate with that of a swap 1/1000 the
+// size, which approximates the pool's marginal price.
+func PreviewFeeSwap(ctx context.Context, out io.Writer, cfg *Config, wallet *sequence.Wallet[*v3.WalletConfig], provider *ethrpc.Provider, call CallSpec) error {
+	if cfg.FeeAutoSwap == nil {
+		return errors.New("feeAutoSwap is not configured")
+	}
+	router := common.HexToAddress(cfg.FeeAutoSwap.Router)
+	fromToken := common.HexToAddress(cfg.FeeAutoSwap.FromToken)
+	walletAddr := wallet.Address()
+
+	txs, err := buildMintTransaction(wallet, call, 1)
+	if err != nil {
+		return err
+	}
+	options, _, err := wallet.FeeOptions(ctx, txs)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errFetchFeeOptions, err)
+	}
+	if len(options) == 0 {
+		fmt.Fprintln(out, "The relayer quoted no fee options; no swap would run.")
+		return nil
+	}
+	resolveFeeTokenDecimals(ctx, out, cfg, provider, options)
+
+	// The preview reads fresh balances; nothing is cached across runs.
+	balances := newBalanceCache(0)
+	fromBalance, err := balances.erc20Balance(ctx, provider, fromToken, walletAddr)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "\n--- Fee swap preview (router %s, slippage %d bps) ---\n", router.Hex(), cfg.FeeAutoSwap.slippageBps())
+	fmt.Fprintf(out, "Wallet holds %s of %s\n", fromBalance, fromToken.Hex())
+	quoted := 0
+	for _, option := range options {
+		if isNativeFeeOption(option) || option.Token.Type != sequence.ERC20_TOKEN || option.Token.ContractAddress == nil {
+			continue
+		}
+		feeToken := *option.Token.ContractAddress
+		switch {
+		case feeToken == fromToken:
+			fmt.Fprintf(out, "%s: fee is paid in the source token; no swap\n", option.Token.Symbol)
+			continue
+		case !cfg.isAllowedFeeToken(option):
+			fmt.Fprintf(out, "%s: not on feeTokenAllowlist; skipped\n", option.Token.Symbol)
+			continue
+		case !cfg.isExpectedFeeRecipient(option.To):
+			fmt.Fprintf(out, "%s: unexpected fee recipient %s; skipped\n", option.Token.Symbol, option.To.Hex())
+			continue
+		}
+
+		held, err := balances.erc20Balance(ctx, provider, feeToken, walletAddr)
+		if err != nil {
+			return err
+		}
+		fee := feeOptionValue(option)
+		shortfall := new(big.Int).Sub(fee, held)
+		fmt.Fprintf(out, "%s: route %s -> %s, fee %s, wallet holds %s\n", option.Token.Symbol, fromToken.Hex(), feeToken.Hex(),
+			cfg.NumberFormat.fee(option), cfg.NumberFormat.amount(held, option.Token.Decimals))
+		if shortfall.Sign() <= 0 {
+			fmt.Fprintln(out, "  no swap needed; the fee is covered")
+			continue
+		}
+
+		amountIn, err := quoteSwapAmountIn(ctx, provider, router, fromToken, feeToken, shortfall)
+		if err != nil {
+			fmt.Fprintf(out, "  no quote: %v\n", err)
+			continue
+		}
+		quoted++
+		amountInMax := new(big.Int).Mul(amountIn, big.NewInt(10_000+cfg.FeeAutoSwap.slippageBps()))
+		amountInMax.Div(amountInMax, big.NewInt(10_000))
+		fmt.Fprintf(out, "  swap for %s out: quoted %s in, at most %s in with slippage\n", shortfall, amountIn, amountInMax)
+
+		small := new(big.Int).Div(shortfall, big.NewInt(1000))
+		if small.Sign() > 0 {
+			if smallIn, err := quoteSwapAmountIn(ctx, provider, router, fromToken, feeToken, small); err == nil && smallIn.Sign() > 0 {
+				// impact = (amountIn/shortfall) / (smallIn/small) - 1, in bps.
+				num := new(big.Int).Mul(amountIn, small)
+				den := new(big.Int).Mul(smallIn, shortfall)
+				impact := new(big.Int).Div(new(big.Int).Mul(new(big.Int).Sub(num, den), big.NewInt(10_000)), den)
+				fmt.Fprintf(out, "  price impact: ~%s bps\n", impact)
+			}
+		}
+		if fromBalance.Cmp(amountInMax) < 0 {
+			fmt.Fprintln(out, "  not affordable: the wallet holds less than the maximum input")
+		}
+	}
+	if quoted == 0 {
+		fmt.Fprintln(out, "No swap quote was obtained.")
+		return nil
+	}
+
+	best, err := planFeeSwap(ctx, out, cfg, provider, balances, walletAddr, options, call.Value)
+	if err != nil {
+		fmt.Fprintf(out, "Auto-swap would not run: %v\n", err)
+		return nil
+	}
+	fmt.Fprintf(out, "If no fee option is affordable outright, auto-swap would sell at most %s of %s for %s %s\n", best.AmountInMax, fromToken.Hex(), best.AmountOut, best.Option.Token.Symbol)
+	return nil
+}
+
+// quoteSwapAmountIn asks the router how much of tokenIn buys amountOut of
+// tokenOut over the direct pair.
+func quoteSwapAmountIn(ctx context.Context, provider *ethrpc.Provider, router, tokenIn, tokenOut common.Address, amountOut *big.Int) (*big.Int, error) {
+	calldata, err := swapRouterABI.Pack("getAmountsIn", amountOut, []common.Address{tokenIn, tokenOut})
+	if err != nil {
+		return nil, fmt.Errorf("encode getAmountsIn: %w", err)
+	}
+
+	output, err := provider.CallContract(ctx, ethereum.CallMsg{To: &router, Data: calldata}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("getAmountsIn call: %w", err)
+	}
+
+	results, err := swapRouterABI.Unpack("getAmountsIn", output)
+	if err != nil {
+		return nil, fmt.Errorf("decode getAmountsIn: %w", err)
+	}
+
+	amounts, ok := results[0].([]*big.Int)
+	if !ok || len(amounts) != 2 {
+		return nil, fmt.Errorf("unexpected getAmountsIn result %v", results[0])
+	}
+	// The swap must leave the wallet holding at least the fee.
+	if amounts[1].Cmp(amountOut) < 0 {
+		return nil, fmt.Errorf("router quotes %s out, short of %s", amounts[1], amountOut)
+	}
+	return amounts[0], nil
+}
+
+// buildFeeSwapTransactions returns the approve and swapTokensForExactTokens
+// calls that execute swap, to be placed ahead of the fee payment.
+func buildFeeSwapTransactions(cfg *Config, walletAddr common.Address, swap *feeSwap) (sequence.Transactions, error) {
+	router := common.HexToAddress(cfg.FeeAutoSwap.Router)
+	fromToken := common.HexToAddress(cfg.FeeAutoSwap.FromToken)
+	path := []common.Address{fromToken, *swap.Option.Token.ContractAddress}
+	deadline := big.NewInt(time.Now().Add(waitTimeout).Unix())
+
+	approveData, err := erc20TokenABI.Pack("approve", router, swap.AmountInMax)
+	if err != nil {
+		return nil, fmt.Errorf("encode erc20 approve: %w", err)
+	}
+	swapData, err := swapRouterABI.Pack("swapTokensForExactTokens", swap.AmountOut, swap.AmountInMax, path, walletAddr, deadline)
+	if err != nil {
+		return nil, fmt.Errorf("encode swapTokensForExactTokens: %w", err)
+	}
+
+	return sequence.Transactions{
+		{To: fromToken, Value: big.NewInt(0), Data: approveData, GasLimit: autoGasLimit(), RevertOnError: true},
+		{To: router, Value: big.NewInt(0), Data: swapData, GasLimit: autoGasLimit(), RevertOnError: true},
+	}, nil
+}
+
+// Approval is an ERC-20 allowance granted by a relayed bundle: a non-zero
+// approve(Spender, amount) call on Token, such as the one letting the
+// feeAutoSwap router pull the source token.
+type Approval struct {
+	Token   common.Address
+	Spender common.Address
+}
+
+// bundleApprovals returns the approvals granted by txs, in order.
+func bundleApprovals(txs sequence.Transactions) []Approval {
+	approve := erc20TokenABI.Methods["approve"]
+	var approvals []Approval
+	for _, txn := range txs {
+		if len(txn.Data) < 4 || !bytes.Equal(txn.Data[:4], approve.ID) {
+			continue
+		}
+		args, err := approve.Inputs.Unpack(txn.Data[4:])
+		if err != nil {
+			continue
+		}
+		spender, _ := args[0].(common.Address)
+		amount, _ := args[1].(*big.Int)
+		if amount == nil || amount.Sign() == 0 {
+			continue
+		}
+		approvals = append(approvals, Approval{Token: txn.To, Spender: spender})
+	}
+	return approvals
+}
+
+// errAllowanceNotRevoked is returned when an allowance is still non-zero
+// after its revoke confirmed.
+var errAllowanceNotRevoked = errors.New("allowance not revoked")
+
+// RevokeAllowances zeroes each allowance the bundles in results granted,
+// such as the feeAutoSwap router's. Allowances already back at zero, e.g.
+// because the swap spent all of it, are left alone. Each revoke is relayed
+// on its own once the run has confirmed, and the allowance is read back
+// afterward.
+func (r *Runner) RevokeAllowances(ctx context.Context, opts SendOptions, results []TxResult) error {
+	return r.revokeAllowances(ctx, r.Wallet.Address(), results, func(ctx context.Context, txn *sequence.Transaction) (sequence.MetaTxnID, *types.Receipt, error) {
+		bundle, err := r.SendTransactionsWithFees(ctx, opts, sequence.Transactions{txn}, nil)
+		if err != nil {
+			return "", nil, err
+		}
+		opts.BundleOrder++
+		receipt, err := waitForRelayedReceipt(ctx, r.out(), r.Config, r.Provider, bundle)
+		return bundle.MetaTxnID, receipt, err
+	})
+}
+
+// revokeAllowances does the work of RevokeAllowances for the allowances
+// owner granted, relaying each revoke through relay, which returns once the
+// revoke has a receipt.
+func (r *Runner) revokeAllowances(ctx context.Context, owner common.Address, results []TxResult, relay func(context.Context, *sequence.Transaction) (sequence.MetaTxnID, *types.Receipt, error)) error {
+	out := r.out()
+	cfg, provider := r.Config, r.Provider
+	var approvals []Approval
+	seen := map[Approval]bool{}
+	for _, result := range results {
+		for _, a := range result.Approvals {
+			if !seen[a] {
+				seen[a] = true
+				approvals = append(approvals, a)
+			}
+		}
+	}
+	if len(approvals) == 0 {
+		fmt.Fprintln(out, "Revoke allowance: the run granted no allowance; nothing to revoke")
+		return nil
+	}
+
+	// The allowances are read together up front when a multicall is
+	// available; revokes and swaps don't touch the others'.
+	initial := make([]*big.Int, len(approvals))
+	if multicall, ok := cfg.multicall(); ok && len(approvals) > 1 {
+		queries := make([]allowanceQuery, len(approvals))
+		for i, a := range approvals {
+			queries[i] = allowanceQuery{Token: a.Token, Spender: a.Spender}
+		}
+		allowances, err := multicallAllowances(ctx, provider, multicall, owner, queries)
+		if err != nil {
+			fmt.Fprintf(out, "Note: multicall allowance batch failed (%v); checking allowances one by one\n", err)
+		} else {
+			initial = allowances
+		}
+	}
+	for i, a := range approvals {
+		allowance := initial[i]
+		if allowance == nil {
+			var err error
+			allowance, err = erc20Allowance(ctx, provider, a.Token, owner, a.Spender)
+			if err != nil {
+				return fmt.Errorf("%s allowance: %w", a.Token.Hex(), err)
+			}
+		}
+		if allowance.Sign() == 0 {
+			fmt.Fprintf(out, "Revoke allowance: %s allowance for %s is already 0; no revoke performed\n", a.Token.Hex(), a.Spender.Hex())
+			continue
+		}
+
+		calldata, err := erc20TokenABI.Pack("approve", a.Spender, big.NewInt(0))
+		if err != nil {
+			return fmt.Errorf("encode erc20 approve: %w", err)
+		}
+		txn := &sequence.Transaction{To: a.Token, Value: big.NewInt(0), Data: calldata, GasLimit: autoGasLimit(), RevertOnError: true}
+
+		fmt.Fprintf(out, "Revoking %s allowance of %s for %s...\n", a.Token.Hex(), allowance, a.Spender.Hex())
+		metaTxnID, receipt, err := relay(ctx, txn)
+		if err != nil {
+			return WithMetaTxnID(metaTxnID, fmt.Errorf("revoke %s allowance: %w", a.Token.Hex(), err))
+		}
+		if receipt.Status != types.ReceiptStatusSuccessful {
+			return WithMetaTxnID(metaTxnID, fmt.Errorf("%s revoke %s reverted", a.Token.Hex(), receipt.TxHash.Hex()))
+		}
+
+		allowance, err = erc20Allowance(ctx, provider, a.Token, owner, a.Spender)
+		if err != nil {
+			return fmt.Errorf("%s allowance after revoke: %w", a.Token.Hex(), err)
+		}
+		if allowance.Sign() != 0 {
+			return WithMetaTxnID(metaTxnID, fmt.Errorf("%w: %s allowance for %s is %s after %s", errAllowanceNotRevoked, a.Token.Hex(), a.Spender.Hex(), allowance, receipt.TxHash.Hex()))
+		}
+		fmt.Fprintf(out, "Revoke allowance: revoked %s allowance for %s in %s\n", a.Token.Hex(), a.Spender.Hex(), receipt.TxHash.Hex())
+	}
+	return nil
+}
+
+// tokenDecimalsCache holds decimals read on-chain, keyed by token address.
+var tokenDecimalsCache sync.Map // common.Address -> uint32
+
+// resolveFeeTokenDecimals sets the decimals of each ERC-20 fee option. A
+// cfg.TokenDecimals entry always wins. Otherwise the relayer's value is kept;
+// when the relayer gave none, the token's decimals() is read through
+// provider (once per token per run), defaulting to 18 if that fails. A nil
+// provider skips the on-chain lookup.
+func resolveFeeTokenDecimals(ctx context.Context, out io.Writer, cfg *Config, provider *ethrpc.Provider, options []*sequence.RelayerFeeOption) {
+	for _, option := range options {
+		if isNativeFeeOption(option) || option.Token.ContractAddress == nil {
+			continue
+		}
+		token := *option.Token.ContractAddress
+		if decimals, ok := cfg.tokenDecimals(token); ok {
+			option.Token.Decimals = &decimals
+			continue
+		}
+		if option.Token.Decimals != nil || provider == nil {
+			continue
+		}
+
+		if cached, ok := tokenDecimalsCache.Load(token); ok {
+			decimals := cached.(uint32)
+			option.Token.Decimals = &decimals
+			continue
+		}
+		decimals, err := erc20Decimals(ctx, provider, token)
+		if err != nil {
+			fmt.Fprintf(out, "Warning: could not read decimals of fee token %s (%v); assuming %d\n", token.Hex(), err, nativeTokenDecimals)
+			decimals = nativeTokenDecimals
+		}
+		tokenDecimalsCache.Store(token, decimals)
+		option.Token.Decimals = &decimals
+	}
+}
+
+// erc20Decimals calls decimals() on token.
+func erc20Decimals(ctx context.Context, provider *ethrpc.Provider, token common.Address) (uint32, error) {
+	calldata, err := erc20TokenABI.Pack("decimals")
+	if err != nil {
+		return 0, fmt.Errorf("encode decimals: %w", err)
+	}
+
+	output, err := provider.CallContract(ctx, ethereum.CallMsg{To: &token, Data: calldata}, nil)
+	if err != nil {
+		return 0, fmt.Errorf("decimals call: %w", err)
+	}
+
+	results, err := erc20TokenABI.Unpack("decimals", output)
+	if err != nil {
+		return 0, fmt.Errorf("decode decimals: %w", err)
+	}
+	decimals, ok := results[0].(uint8)
+	if !ok {
+		return 0, fmt.Errorf("unexpected decimals result type %T", results[0])
+	}
+	return uint32(decimals), nil
+}
+
+// erc20Allowance returns how much of token spender may move from owner.
+func erc20Allowance(ctx context.Context, provider *ethrpc.Provider, token, owner, spender common.Address) (*big.Int, error) {
+	calldata, err := erc20TokenABI.Pack("allowance", owner, spender)
+	if err != nil {
+		return nil, fmt.Errorf("encode allowance: %w", err)
+	}
+
+	output, err := provider.CallContract(ctx, ethereum.CallMsg{To: &token, Data: calldata}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("allowance call: %w", err)
+	}
+
+	results, err := erc20TokenABI.Unpack("allowance", output)
+	if err != nil {
+		return nil, fmt.Errorf("decode allowance: %w", err)
+	}
+	allowance, ok := results[0].(*big.Int)
+	if !ok {
+		return nil, fmt.Errorf("unexpected allowance result type %T", results[0])
+	}
+	return allowance, nil
+}
+
+// describeFeeTransaction decodes feeTxn, the payment built for option, into
+// labeled lines. The recipient and amount are read back from the encoded
+// transaction rather than the option, so they show what will actually be
+// sent.
+func describeFeeTransaction(nf *NumberFormat, option *sequence.RelayerFeeOption, feeTxn *sequence.Transaction) []string {
+	lines := []string{"Fee transaction:"}
+
+	if isNativeFeeOption(option) {
+		return append(lines,
+			"  kind:      native transfer",
+			"  recipient: "+feeTxn.To.Hex(),
+			"  amount:    "+nf.fee(&sequence.RelayerFeeOption{Token: option.Token, Value: feeTxn.Value}),
+			"  selector:  none",
+			"  gas limit: "+formatGasLimit(feeTxn.GasLimit),
+		)
+	}
+
+	lines = append(lines,
+		"  kind:      ERC-20 transfer",
+		"  token:     "+feeTxn.To.Hex()+" ("+option.Token.Symbol+")",
+	)
+	if len(feeTxn.Data) < 4 {
+		return append(lines, "  calldata:  missing selector")
+	}
+
+	method, err := erc20TokenABI.MethodById(feeTxn.Data[:4])
+	if err != nil {
+		return append(lines, fmt.Sprintf("  selector:  0x%x (unknown)", feeTxn.Data[:4]))
+	}
+	args, err := method.Inputs.Unpack(feeTxn.Data[4:])
+	if err != nil || len(args) != 2 {
+		return append(lines, fmt.Sprintf("  selector:  0x%x %s (undecodable arguments)", feeTxn.Data[:4], method.Sig))
+	}
+	recipient, _ := args[0].(common.Address)
+	amount, _ := args[1].(*big.Int)
+
+	return append(lines,
+		"  recipient: "+recipient.Hex(),
+		"  amount:    "+nf.fee(&sequence.RelayerFeeOption{Token: option.Token, Value: amount}),
+		fmt.Sprintf("  selector:  0x%x %s", feeTxn.Data[:4], method.Sig),
+		"  gas limit: "+formatGasLimit(feeTxn.GasLimit),
+	)
+}
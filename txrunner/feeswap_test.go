@@ -0,0 +1,93 @@
+package txrunner
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/0xsequence/ethkit/ethrpc"
+	"github.com/0xsequence/ethkit/go-ethereum/common"
+	"github.com/0xsequence/ethkit/go-ethereum/core/types"
+	sequence "github.com/0xsequence/go-sequence"
+)
+
+func TestRevokeAllowancesZeroesSwapRouterAllowance(t *testing.T) {
+	ctx := context.Background()
+	node := newFakeNode(t)
+	owner := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	weth := common.HexToAddress("0x0000000000000000000000000000000000000b01")
+	usdc := common.HexToAddress("0x0000000000000000000000000000000000000b02")
+	router := common.HexToAddress("0x0000000000000000000000000000000000000d01")
+	collector := common.HexToAddress("0x0000000000000000000000000000000000000c01")
+	node.balances[weth] = big.NewInt(5_000)
+	node.balances[usdc] = new(big.Int)
+
+	cfg := &Config{FeeAutoSwap: &FeeAutoSwapConfig{Router: router.Hex(), FromToken: weth.Hex()}}
+	swap := &feeSwap{
+		Option: &sequence.RelayerFeeOption{
+			Token: sequence.RelayerFeeToken{Type: sequence.ERC20_TOKEN, ContractAddress: &usdc, Symbol: "USDC"},
+			To:    collector,
+			Value: big.NewInt(100),
+		},
+		AmountOut:   big.NewInt(100),
+		AmountInMax: big.NewInt(1_000),
+	}
+	swapTxns, err := buildFeeSwapTransactions(cfg, owner, swap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	approvals := bundleApprovals(swapTxns)
+	if want := (Approval{Token: weth, Spender: router}); len(approvals) != 1 || approvals[0] != want {
+		t.Fatalf("swap approvals: got %v, want [%v]", approvals, want)
+	}
+
+	// The swap spent less than the slippage allowed for, leaving the rest
+	// of the approval standing.
+	query := allowanceQuery{Token: weth, Spender: router}
+	node.mu.Lock()
+	node.allowances[query] = big.NewInt(40)
+	node.mu.Unlock()
+
+	provider, err := ethrpc.NewProvider(node.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runner := NewRunner(cfg, nil, provider, nil, nil)
+
+	// relay runs each revoke as the chain would: an approve on the token
+	// sets the owner's allowance for the spender.
+	var revokes []*sequence.Transaction
+	relay := func(ctx context.Context, txn *sequence.Transaction) (sequence.MetaTxnID, *types.Receipt, error) {
+		revokes = append(revokes, txn)
+		args, err := erc20TokenABI.Methods["approve"].Inputs.Unpack(txn.Data[4:])
+		if err != nil {
+			t.Fatalf("revoke is not an approve: %v", err)
+		}
+		node.mu.Lock()
+		node.allowances[allowanceQuery{Token: txn.To, Spender: args[0].(common.Address)}] = args[1].(*big.Int)
+		node.mu.Unlock()
+		return "0x01", &types.Receipt{Status: types.ReceiptStatusSuccessful}, nil
+	}
+
+	results := []TxResult{{Fee: swap.Option, Approvals: approvals}, {Fee: swap.Option, Approvals: approvals}}
+	if err := runner.revokeAllowances(ctx, owner, results, relay); err != nil {
+		t.Fatalf("revokeAllowances: %v", err)
+	}
+	if len(revokes) != 1 || revokes[0].To != weth {
+		t.Fatalf("got %d revokes, want one on %s", len(revokes), weth.Hex())
+	}
+	node.mu.Lock()
+	allowance := node.allowances[query]
+	node.mu.Unlock()
+	if allowance.Sign() != 0 {
+		t.Errorf("router allowance after revoke: got %v, want 0", allowance)
+	}
+
+	// Run again, the allowance is already zero and nothing is relayed.
+	if err := runner.revokeAllowances(ctx, owner, results, relay); err != nil {
+		t.Fatalf("revokeAllowances: %v", err)
+	}
+	if len(revokes) != 1 {
+		t.Errorf("revoked a zero allowance: got %d revokes, want 1", len(revokes))
+	}
+}
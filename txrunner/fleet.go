@@ -0,0 +1,258 @@
+package txrunner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/0xsequence/ethkit/ethrpc"
+	"github.com/0xsequence/ethkit/ethwallet"
+	"github.com/0xsequence/ethkit/go-ethereum/common"
+	sequence "github.com/0xsequence/go-sequence"
+	v3 "github.com/0xsequence/go-sequence/core/v3"
+)
+
+// ---------------------------------------------------------------------------
+// Fleet provisioning — bulk operations over a list of wallet owners
+// ---------------------------------------------------------------------------
+
+const FleetUsage = `  publish-fleet <wallet-list>   publish every wallet's config to the directory
+  deploy-fleet <wallet-list>    deploy every wallet from the signer EOA
+`
+
+// Fleet statuses reported per wallet.
+const (
+	fleetPublished       = "published"
+	fleetAlreadyExists   = "already-exists"
+	fleetDeployed        = "deployed"
+	fleetAlreadyDeployed = "already-deployed"
+	fleetSkipped         = "skipped"
+	fleetFailed          = "failed"
+)
+
+// fleetResult is the outcome of a fleet operation for one wallet.
+type fleetResult struct {
+	Owner   common.Address
+	Wallet  common.Address
+	Status  string
+	Err     error
+	Elapsed time.Duration
+}
+
+// ReadWalletList reads owner addresses, one per line, from path. Blank lines
+// and lines starting with "#" are ignored; duplicates are rejected.
+func ReadWalletList(path string) ([]common.Address, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var owners []common.Address
+	seen := make(map[common.Address]int)
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !common.IsHexAddress(line) {
+			return nil, fmt.Errorf("%s:%d: invalid owner address %q", path, i+1, line)
+		}
+		owner := common.HexToAddress(line)
+		if prev, ok := seen[owner]; ok {
+			return nil, fmt.Errorf("%s:%d: duplicate owner %s (first on line %d)", path, i+1, owner.Hex(), prev)
+		}
+		seen[owner] = i + 1
+		owners = append(owners, owner)
+	}
+	if len(owners) == 0 {
+		return nil, fmt.Errorf("%s: no owner addresses", path)
+	}
+	return owners, nil
+}
+
+// runFleet calls fn for every owner's wallet, at most cfg.fleetConcurrency()
+// at a time, and returns the results in owner order. fn returns the wallet's
+// status; an error without a status is reported as failed.
+func runFleet(cfg *Config, owners []common.Address, fn func(wallet *sequence.Wallet[*v3.WalletConfig]) (string, error)) []fleetResult {
+	results := make([]fleetResult, len(owners))
+	sem := make(chan struct{}, cfg.fleetConcurrency())
+	var wg sync.WaitGroup
+
+	for i, owner := range owners {
+		wg.Add(1)
+		go func(idx int, owner common.Address) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			start := time.Now()
+			result := fleetResult{Owner: owner}
+			wallet, err := newOwnerWallet(cfg, owner)
+			if err == nil {
+				result.Wallet = wallet.Address()
+				result.Status, err = fn(wallet)
+			}
+			if err != nil {
+				result.Err = err
+				if result.Status == "" {
+					result.Status = fleetFailed
+				}
+			}
+			result.Elapsed = time.Since(start)
+			results[idx] = result
+		}(i, owner)
+	}
+
+	wg.Wait()
+	return results
+}
+
+// RunFleetCommand runs the fleet subcommand name over owners, deploying
+// from the runner's deployer EOA.
+func (r *Runner) RunFleetCommand(ctx context.Context, name string, owners []common.Address) error {
+	if name == "publish-fleet" {
+		return publishFleet(ctx, r.out(), r.Config, owners)
+	}
+	if r.Deployer == nil {
+		return errors.New("no deployer to send from")
+	}
+	fmt.Fprintf(r.out(), "Deployer Address (EOA): %s\n", r.Deployer.Address().Hex())
+	return deployFleet(ctx, r.out(), r.Config, r.Provider, r.Deployer, owners)
+}
+
+// deployFleet deploys every owner's wallet from the deployer EOA. Sends are
+// serialized so each takes the next EOA nonce, while confirmations are
+// awaited in parallel. Once the EOA can't pay for a deployment, the wallets
+// not yet sent are skipped.
+func deployFleet(ctx context.Context, out io.Writer, cfg *Config, provider *ethrpc.Provider, deployer *ethwallet.Wallet, owners []common.Address) error {
+	chainID, err := provider.ChainID(ctx)
+	if err != nil {
+		return fmt.Errorf("fetch chain id: %w", err)
+	}
+	nonce, err := provider.PendingNonceAt(ctx, deployer.Address())
+	if err != nil {
+		return fmt.Errorf("fetch deployer nonce: %w", err)
+	}
+
+	if err := checkDeployGasLimit(ctx, out, provider, cfg.deployGasLimit()); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "\nDeploying %d wallets (concurrency %d, starting at nonce %d)...\n", len(owners), cfg.fleetConcurrency(), nonce)
+
+	var (
+		sendMu     sync.Mutex
+		outOfFunds bool
+	)
+	start := time.Now()
+	results := runFleet(cfg, owners, func(wallet *sequence.Wallet[*v3.WalletConfig]) (string, error) {
+		code, err := provider.CodeAt(ctx, wallet.Address(), nil)
+		if err != nil {
+			return "", fmt.Errorf("check deployment: %w", err)
+		}
+		if len(code) > 0 {
+			return fleetAlreadyDeployed, nil
+		}
+		if cfg.DeployTxLog != "" {
+			deployed, err := awaitPendingDeployment(ctx, out, cfg, provider, wallet.Address())
+			if err != nil {
+				return "", err
+			}
+			if deployed {
+				return fleetDeployed, nil
+			}
+		}
+
+		_, factoryAddress, deployData, err := sequence.EncodeWalletDeployment(wallet.GetWalletConfig(), wallet.GetWalletContext())
+		if err != nil {
+			return "", fmt.Errorf("encode deployment: %w", err)
+		}
+
+		gasLimit := cfg.deployGasLimit()
+		sendMu.Lock()
+		if outOfFunds {
+			sendMu.Unlock()
+			return fleetSkipped, errors.New("deployer EOA ran out of funds")
+		}
+		nativeTx, waitDeploy, err := sendDeployment(ctx, cfg, provider, deployer, chainID, factoryAddress, deployData, gasLimit, new(big.Int).SetUint64(nonce))
+		if err == nil {
+			nonce++
+		} else if isInsufficientFundsError(err) {
+			outOfFunds = true
+		}
+		sendMu.Unlock()
+		if err != nil {
+			return "", err
+		}
+
+		fmt.Fprintf(out, "Deployment of %s sent: %s\n", wallet.Address().Hex(), nativeTx.Hash().Hex())
+		if cfg.DeployTxLog != "" {
+			if err := recordDeployTx(cfg.DeployTxLog, wallet.Address(), nativeTx.Hash()); err != nil {
+				fmt.Fprintf(out, "Warning: could not record deployment tx for %s: %v\n", wallet.Address().Hex(), err)
+			}
+		}
+		if err := awaitDeployment(ctx, nativeTx, waitDeploy, gasLimit); err != nil {
+			return "", err
+		}
+		return fleetDeployed, nil
+	})
+
+	return printFleetSummary(out, results, time.Since(start))
+}
+
+// publishFleet publishes the config of every owner's wallet to the
+// directory. Wallets the directory already knows are skipped.
+func publishFleet(ctx context.Context, out io.Writer, cfg *Config, owners []common.Address) error {
+	fmt.Fprintf(out, "\nPublishing %d wallet configs (concurrency %d)...\n", len(owners), cfg.fleetConcurrency())
+
+	sessions := NewSessionsClient(cfg)
+	start := time.Now()
+	results := runFleet(cfg, owners, func(wallet *sequence.Wallet[*v3.WalletConfig]) (string, error) {
+		if deployHash, _, err := sessions.DeployHash(ctx, wallet.Address().Hex()); err == nil && deployHash != "" {
+			return fleetAlreadyExists, nil
+		}
+		if err := PublishWalletConfig(ctx, wallet, sessions); err != nil {
+			return "", err
+		}
+		return fleetPublished, nil
+	})
+
+	return printFleetSummary(out, results, time.Since(start))
+}
+
+// printFleetSummary prints one line per wallet and per-status totals. It
+// returns an error if any wallet failed.
+func printFleetSummary(w io.Writer, results []fleetResult, elapsed time.Duration) error {
+	fmt.Fprintln(w, "\n--- Fleet Results ---")
+	fmt.Fprintf(w, "%-42s %-42s %-8s %s\n", "Owner", "Wallet", "Time", "Status")
+	fmt.Fprintln(w, strings.Repeat("-", 110))
+
+	counts := make(map[string]int)
+	for _, r := range results {
+		status := r.Status
+		if r.Err != nil {
+			status = fmt.Sprintf("%s: %v", r.Status, r.Err)
+		}
+		counts[r.Status]++
+		fmt.Fprintf(w, "%-42s %-42s %-8s %s\n", r.Owner.Hex(), r.Wallet.Hex(), r.Elapsed.Round(time.Millisecond), status)
+	}
+
+	fmt.Fprintf(w, "\nTotal: %d", len(results))
+	for _, status := range []string{fleetPublished, fleetAlreadyExists, fleetDeployed, fleetAlreadyDeployed, fleetSkipped, fleetFailed} {
+		if counts[status] > 0 {
+			fmt.Fprintf(w, " | %s: %d", status, counts[status])
+		}
+	}
+	fmt.Fprintf(w, " | Elapsed: %s\n", elapsed.Round(time.Millisecond))
+
+	if counts[fleetFailed] > 0 || counts[fleetSkipped] > 0 {
+		return fmt.Errorf("%d of %d wallets failed, %d skipped", counts[fleetFailed], len(results), counts[fleetSkipped])
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package txrunner
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	sequence "github.com/0xsequence/go-sequence"
+)
+
+// ---------------------------------------------------------------------------
+// Amount formatting
+// ---------------------------------------------------------------------------
+
+// amount renders v, a base-unit amount of a token with the given decimals.
+// With nil decimals only the integer grouping is applied. A nil or raw
+// format returns v unchanged.
+func (nf *NumberFormat) amount(v *big.Int, decimals *uint32) string {
+	if v == nil {
+		v = big.NewInt(0)
+	}
+	if nf == nil || nf.Raw {
+		return v.String()
+	}
+
+	sign := ""
+	if v.Sign() < 0 {
+		sign = "-"
+	}
+	abs := new(big.Int).Abs(v)
+
+	if decimals == nil || *decimals == 0 {
+		return sign + groupDigits(abs.String(), nf.ThousandsSeparator)
+	}
+
+	unit := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(*decimals)), nil)
+	whole, frac := new(big.Int).QuoRem(abs, unit, new(big.Int))
+
+	places := defaultDisplayDecimals
+	if nf.Decimals != nil {
+		places = *nf.Decimals
+	}
+	fracDigits := fmt.Sprintf("%0*s", int(*decimals), frac.String())
+	if places < len(fracDigits) {
+		fracDigits = fracDigits[:places]
+	}
+	fracDigits = strings.TrimRight(fracDigits, "0")
+
+	decimalSep := nf.DecimalSeparator
+	if decimalSep == "" {
+		decimalSep = "."
+	}
+
+	// Don't let truncation print a non-zero amount as zero.
+	if whole.Sign() == 0 && fracDigits == "" && abs.Sign() > 0 {
+		if places == 0 {
+			return sign + "<1"
+		}
+		return sign + "<0" + decimalSep + strings.Repeat("0", places-1) + "1"
+	}
+
+	out := sign + groupDigits(whole.String(), nf.ThousandsSeparator)
+	if fracDigits != "" {
+		out += decimalSep + fracDigits
+	}
+	return out
+}
+
+// fee renders the value and symbol of a relayer fee option.
+func (nf *NumberFormat) fee(option *sequence.RelayerFeeOption) string {
+	decimals := option.Token.Decimals
+	if decimals == nil && isNativeFeeOption(option) {
+		native := uint32(nativeTokenDecimals)
+		decimals = &native
+	}
+	return nf.amount(feeOptionValue(option), decimals) + " " + option.Token.Symbol
+}
+
+// Native renders an amount of the chain's native token: in wei when raw,
+// otherwise in whole units of the native symbol, if known.
+func (nf *NumberFormat) Native(v *big.Int) string {
+	if nf == nil || nf.Raw {
+		return nf.amount(v, nil) + " wei"
+	}
+	symbol := nf.nativeSymbol
+	if symbol == "" {
+		symbol = "native"
+	}
+	decimals := uint32(nativeTokenDecimals)
+	return nf.amount(v, &decimals) + " " + symbol
+}
+
+// groupDigits inserts sep between every three digits of a non-negative
+// integer string.
+func groupDigits(digits, sep string) string {
+	if sep == "" || len(digits) <= 3 {
+		return digits
+	}
+	var b strings.Builder
+	lead := len(digits) % 3
+	if lead > 0 {
+		b.WriteString(digits[:lead])
+	}
+	for i := lead; i < len(digits); i += 3 {
+		if b.Len() > 0 {
+			b.WriteString(sep)
+		}
+		b.WriteString(digits[i : i+3])
+	}
+	return b.String()
+}
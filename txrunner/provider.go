@@ -0,0 +1,290 @@
+package txrunner
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math/big"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/0xsequence/ethkit/ethrpc"
+	"golang.org/x/sync/singleflight"
+)
+
+// ---------------------------------------------------------------------------
+// Node provider — JSON-RPC transport and request ids
+// ---------------------------------------------------------------------------
+
+// httpDoer sends HTTP requests; the provider's HTTP client and the wrappers
+// below implement it.
+type httpDoer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// NewProvider returns a provider for nodeURL. Unless request ids are
+// sequential, which ethrpc does itself, its HTTP client is wrapped to
+// rewrite them. With advanced.coalesceReads it is also wrapped to coalesce
+// concurrent identical reads.
+func NewProvider(out io.Writer, cfg *Config, nodeURL string) (*ethrpc.Provider, error) {
+	strategy := cfg.rpcRequestIDs()
+	coalesce := cfg.Advanced != nil && cfg.Advanced.CoalesceReads
+	if strategy == rpcIDsSequential && !coalesce {
+		return ethrpc.NewProvider(nodeURL)
+	}
+
+	var client httpDoer = &http.Client{Timeout: 35 * time.Second}
+	if strategy != rpcIDsSequential {
+		idClient := &rpcIDClient{
+			client: client,
+			issued: make(map[uint64]struct{}),
+		}
+		switch strategy {
+		case rpcIDsRandom:
+			idClient.next = idClient.randomID
+		case rpcIDsCorrelation:
+			correlationID := cfg.Advanced.CorrelationID
+			if correlationID == "" {
+				var b [8]byte
+				if _, err := rand.Read(b[:]); err != nil {
+					return nil, fmt.Errorf("generate correlation id: %w", err)
+				}
+				correlationID = hex.EncodeToString(b[:])
+			}
+			h := fnv.New32a()
+			h.Write([]byte(correlationID))
+			idClient.prefix = uint64(h.Sum32()&rpcIDPrefixMask) << 32
+			idClient.next = idClient.correlationID
+			fmt.Fprintf(out, "RPC correlation ID: %s (request ids from %d)\n", correlationID, idClient.prefix+1)
+		}
+		client = idClient
+	}
+	if coalesce {
+		client = &rpcCoalescingClient{client: client}
+	}
+	return ethrpc.NewProvider(nodeURL, ethrpc.WithHTTPClient(client))
+}
+
+// rpcIDPrefixMask keeps correlation ids within 53 bits, so nodes that parse
+// ids as JavaScript numbers see them unchanged.
+const rpcIDPrefixMask = 1<<21 - 1
+
+// rpcIDClient replaces the ids of outgoing JSON-RPC requests with ones from
+// next and restores the originals in the responses, which ethrpc matches
+// against its own ids. No id is issued twice by the same client.
+type rpcIDClient struct {
+	client httpDoer
+	next   func() (uint64, error)
+
+	// prefix and counter make up correlation ids.
+	prefix  uint64
+	counter atomic.Uint32
+
+	mu     sync.Mutex
+	issued map[uint64]struct{}
+}
+
+func (c *rpcIDClient) Do(req *http.Request) (*http.Response, error) {
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("read rpc request: %w", err)
+	}
+
+	// original maps each issued id back to ethrpc's.
+	original := make(map[string]json.RawMessage)
+	body, err = rewriteRPCIDs(body, func(id json.RawMessage) (json.RawMessage, error) {
+		next, err := c.next()
+		if err != nil {
+			return nil, err
+		}
+		issued := json.RawMessage(strconv.FormatUint(next, 10))
+		original[string(issued)] = id
+		return issued, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("rewrite rpc request ids: %w", err)
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	resBody, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("read rpc response: %w", err)
+	}
+	// A response that isn't JSON-RPC, e.g. an HTTP error page, is passed on
+	// as is for ethrpc to report.
+	if restored, err := rewriteRPCIDs(resBody, func(id json.RawMessage) (json.RawMessage, error) {
+		if orig, ok := original[string(id)]; ok {
+			return orig, nil
+		}
+		return id, nil
+	}); err == nil {
+		resBody = restored
+	}
+	res.Body = io.NopCloser(bytes.NewReader(resBody))
+	res.ContentLength = int64(len(resBody))
+	return res, nil
+}
+
+// randomID returns a random 53-bit id not issued before.
+func (c *rpcIDClient) randomID() (uint64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for {
+		var b [8]byte
+		if _, err := rand.Read(b[:]); err != nil {
+			return 0, fmt.Errorf("generate rpc request id: %w", err)
+		}
+		id := new(big.Int).SetBytes(b[:]).Uint64() & (1<<53 - 1)
+		if _, dup := c.issued[id]; id == 0 || dup {
+			continue
+		}
+		c.issued[id] = struct{}{}
+		return id, nil
+	}
+}
+
+// correlationID returns the correlation prefix followed by a per-client
+// sequence number. It fails rather than wrap around and reuse an id.
+func (c *rpcIDClient) correlationID() (uint64, error) {
+	n := c.counter.Add(1)
+	if n == 0 {
+		return 0, errors.New("rpc request ids exhausted for this correlation id")
+	}
+	return c.prefix | uint64(n), nil
+}
+
+// coalescedMethods are the read-only JSON-RPC methods rpcCoalescingClient
+// may answer from another caller's in-flight request.
+var coalescedMethods = map[string]bool{
+	"eth_chainId":    true,
+	"eth_getCode":    true,
+	"eth_getBalance": true,
+	"eth_call":       true,
+}
+
+// rpcCoalescingClient sends concurrent identical requests for one of
+// coalescedMethods to the node once. Every caller gets the shared response
+// with its own request id restored. Batches and other methods pass through.
+// Nothing is cached: a request arriving after the shared one completed is
+// sent again.
+type rpcCoalescingClient struct {
+	client httpDoer
+	group  singleflight.Group
+}
+
+// sharedRPCResponse is a node response read once for all coalesced callers.
+type sharedRPCResponse struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+func (c *rpcCoalescingClient) Do(req *http.Request) (*http.Response, error) {
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("read rpc request: %w", err)
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+
+	var msg struct {
+		ID     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+		Params json.RawMessage `json:"params"`
+	}
+	if err := json.Unmarshal(body, &msg); err != nil || !coalescedMethods[msg.Method] {
+		return c.client.Do(req)
+	}
+
+	key := req.URL.String() + " " + msg.Method + " " + string(msg.Params)
+	v, err, _ := c.group.Do(key, func() (any, error) {
+		res, err := c.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer res.Body.Close()
+		resBody, err := io.ReadAll(res.Body)
+		if err != nil {
+			return nil, fmt.Errorf("read rpc response: %w", err)
+		}
+		return &sharedRPCResponse{status: res.StatusCode, header: res.Header, body: resBody}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	shared := v.(*sharedRPCResponse)
+
+	resBody := shared.body
+	if restored, err := rewriteRPCIDs(resBody, func(json.RawMessage) (json.RawMessage, error) {
+		return msg.ID, nil
+	}); err == nil {
+		resBody = restored
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", shared.status, http.StatusText(shared.status)),
+		StatusCode:    shared.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        shared.header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(resBody)),
+		ContentLength: int64(len(resBody)),
+		Request:       req,
+	}, nil
+}
+
+// rewriteRPCIDs replaces the id of a JSON-RPC message, or of each message in
+// a batch, with fn's result. Other fields are left unchanged.
+func rewriteRPCIDs(body []byte, fn func(id json.RawMessage) (json.RawMessage, error)) ([]byte, error) {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var batch []map[string]json.RawMessage
+		if err := json.Unmarshal(trimmed, &batch); err != nil {
+			return nil, err
+		}
+		for _, msg := range batch {
+			if err := rewriteRPCID(msg, fn); err != nil {
+				return nil, err
+			}
+		}
+		return json.Marshal(batch)
+	}
+
+	var msg map[string]json.RawMessage
+	if err := json.Unmarshal(trimmed, &msg); err != nil {
+		return nil, err
+	}
+	if err := rewriteRPCID(msg, fn); err != nil {
+		return nil, err
+	}
+	return json.Marshal(msg)
+}
+
+func rewriteRPCID(msg map[string]json.RawMessage, fn func(id json.RawMessage) (json.RawMessage, error)) error {
+	id, ok := msg["id"]
+	if !ok {
+		return nil
+	}
+	next, err := fn(id)
+	if err != nil {
+		return err
+	}
+	msg["id"] = next
+	return nil
+}
@@ -0,0 +1,190 @@
+package txrunner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/0xsequence/ethkit/ethrpc"
+	"github.com/0xsequence/ethkit/go-ethereum/common"
+)
+
+// doerFunc adapts a function to httpDoer.
+type doerFunc func(req *http.Request) (*http.Response, error)
+
+func (f doerFunc) Do(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestCoalescingClientSharesConcurrentBalanceReads(t *testing.T) {
+	const callers = 16
+	var upstream, arrived atomic.Int32
+	release := make(chan struct{})
+
+	// The node answers every request with a balance of 100 wei, once
+	// released, echoing the request's id.
+	node := doerFunc(func(req *http.Request) (*http.Response, error) {
+		upstream.Add(1)
+		<-release
+		var msg fakeRPCRequest
+		if err := json.NewDecoder(req.Body).Decode(&msg); err != nil {
+			return nil, err
+		}
+		body, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": msg.ID, "result": "0x64"})
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{"Content-Type": {"application/json"}}, Body: io.NopCloser(bytes.NewReader(body)), Request: req}, nil
+	})
+	coalescing := &rpcCoalescingClient{client: node}
+	entry := doerFunc(func(req *http.Request) (*http.Response, error) {
+		arrived.Add(1)
+		return coalescing.Do(req)
+	})
+	provider, err := ethrpc.NewProvider("http://node.invalid", ethrpc.WithHTTPClient(entry))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	owner := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	balances := make([]*big.Int, callers)
+	errs := make([]error, callers)
+	var wg sync.WaitGroup
+	for i := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			balances[i], errs[i] = provider.BalanceAt(context.Background(), owner, nil)
+		}()
+	}
+	// Hold the node until every caller is in flight, so none of them can
+	// arrive after the shared request completed.
+	for arrived.Load() < callers {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := upstream.Load(); n != 1 {
+		t.Errorf("%d concurrent identical balance reads sent %d requests to the node, want 1", callers, n)
+	}
+	for i := range callers {
+		if errs[i] != nil {
+			t.Errorf("caller %d: %v", i, errs[i])
+		} else if balances[i].Cmp(big.NewInt(100)) != 0 {
+			t.Errorf("caller %d: balance %v, want 100", i, balances[i])
+		}
+	}
+}
+
+func TestRewriteRPCIDs(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    string
+		wantIDs []string // ids passed to fn, in order
+		wantErr bool
+	}{
+		{
+			name:    "numeric id",
+			body:    `{"jsonrpc":"2.0","id":1,"method":"eth_chainId","params":[]}`,
+			want:    `{"jsonrpc":"2.0","id":101,"method":"eth_chainId","params":[]}`,
+			wantIDs: []string{"1"},
+		},
+		{
+			name:    "string id",
+			body:    `{"jsonrpc":"2.0","id":"abc","result":"0x1"}`,
+			want:    `{"jsonrpc":"2.0","id":101,"result":"0x1"}`,
+			wantIDs: []string{`"abc"`},
+		},
+		{
+			name:    "null id",
+			body:    `{"jsonrpc":"2.0","id":null,"error":{"code":-32700,"message":"parse error"}}`,
+			want:    `{"jsonrpc":"2.0","id":101,"error":{"code":-32700,"message":"parse error"}}`,
+			wantIDs: []string{"null"},
+		},
+		{
+			name: "notification without id",
+			body: `{"jsonrpc":"2.0","method":"eth_subscription","params":{"result":"0x1"}}`,
+			want: `{"jsonrpc":"2.0","method":"eth_subscription","params":{"result":"0x1"}}`,
+		},
+		{
+			name:    "batch in order",
+			body:    `[{"jsonrpc":"2.0","id":7,"method":"eth_chainId"},{"jsonrpc":"2.0","id":8,"method":"eth_blockNumber"}]`,
+			want:    `[{"jsonrpc":"2.0","id":101,"method":"eth_chainId"},{"jsonrpc":"2.0","id":102,"method":"eth_blockNumber"}]`,
+			wantIDs: []string{"7", "8"},
+		},
+		{
+			name:    "surrounding whitespace",
+			body:    "\n  [{\"id\":3,\"result\":\"0x0\"}]\n",
+			want:    `[{"id":101,"result":"0x0"}]`,
+			wantIDs: []string{"3"},
+		},
+		{
+			name:    "large id kept exact",
+			body:    `{"id":9007199254740993,"result":"0x0"}`,
+			want:    `{"id":101,"result":"0x0"}`,
+			wantIDs: []string{"9007199254740993"},
+		},
+		{name: "not json", body: `<html>502 Bad Gateway</html>`, wantErr: true},
+		{name: "empty", body: ``, wantErr: true},
+		{name: "batch with a non-object", body: `[1,2]`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ids []string
+			next := 100
+			got, err := rewriteRPCIDs([]byte(tt.body), func(id json.RawMessage) (json.RawMessage, error) {
+				ids = append(ids, string(id))
+				next++
+				return json.RawMessage(strconv.Itoa(next)), nil
+			})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %s, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !jsonEqual(t, got, []byte(tt.want)) {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+			if !slices.Equal(ids, tt.wantIDs) {
+				t.Errorf("fn saw ids %q, want %q", ids, tt.wantIDs)
+			}
+		})
+	}
+
+	t.Run("fn error", func(t *testing.T) {
+		errExhausted := errors.New("ids exhausted")
+		_, err := rewriteRPCIDs([]byte(`[{"id":1},{"id":2}]`), func(json.RawMessage) (json.RawMessage, error) {
+			return nil, errExhausted
+		})
+		if !errors.Is(err, errExhausted) {
+			t.Errorf("got %v, want %v", err, errExhausted)
+		}
+	})
+}
+
+// jsonEqual reports whether a and b hold the same JSON value, ignoring key
+// order and whitespace.
+func jsonEqual(t *testing.T, a, b []byte) bool {
+	t.Helper()
+	var va, vb any
+	if err := json.Unmarshal(a, &va); err != nil {
+		t.Fatalf("unmarshal %s: %v", a, err)
+	}
+	if err := json.Unmarshal(b, &vb); err != nil {
+		t.Fatalf("unmarshal %s: %v", b, err)
+	}
+	return reflect.DeepEqual(va, vb)
+}
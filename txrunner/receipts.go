@@ -0,0 +1,653 @@
+package txrunner
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/0xsequence/ethkit/go-ethereum/common"
+	"github.com/0xsequence/ethkit/go-ethereum/core/types"
+	sequence "github.com/0xsequence/go-sequence"
+)
+
+// ---------------------------------------------------------------------------
+// Receipt storage
+// ---------------------------------------------------------------------------
+
+// ReceiptRecord is the durable record of one relayed meta-transaction.
+// EffectiveGasPrice and GasCost (gas used × effective gas price) are in wei
+// and describe what the relayer paid on-chain, separately from Fee, which is
+// what the wallet paid the relayer.
+type ReceiptRecord struct {
+	OpHash            string              `json:"opHash"`
+	TxHash            string              `json:"txHash"`
+	Status            string              `json:"status"`
+	GasUsed           uint64              `json:"gasUsed"`
+	CumulativeGasUsed uint64              `json:"cumulativeGasUsed"`
+	EffectiveGasPrice string              `json:"effectiveGasPrice,omitempty"`
+	GasCost           string              `json:"gasCost,omitempty"`
+	Fee               *ReceiptFeeRecord   `json:"fee,omitempty"`
+	ChainID           int64               `json:"chainId"`
+	Wallet            string              `json:"wallet"`
+	WalletContext     WalletContextRecord `json:"walletContext"`
+	Label             string              `json:"label,omitempty"`
+	// ConfirmationSeconds is the time from relay to confirmed receipt.
+	ConfirmationSeconds float64 `json:"confirmationSeconds,omitempty"`
+	// Calls is the outcome of each call in the bundle, decoded from the
+	// receipt. Status is "partial" when some of them did not succeed.
+	Calls     []CallOutcome `json:"calls,omitempty"`
+	Timestamp time.Time     `json:"timestamp"`
+}
+
+// WalletContextRecord holds the contract addresses of the wallet context a
+// transaction was relayed under.
+type WalletContextRecord struct {
+	Factory              string `json:"factory"`
+	MainModule           string `json:"mainModule"`
+	MainModuleUpgradable string `json:"mainModuleUpgradable"`
+	GuestModule          string `json:"guestModule"`
+	Utils                string `json:"utils"`
+}
+
+// newWalletContextRecord records the addresses of wc.
+func newWalletContextRecord(wc sequence.WalletContext) WalletContextRecord {
+	return WalletContextRecord{
+		Factory:              wc.FactoryAddress.Hex(),
+		MainModule:           wc.MainModuleAddress.Hex(),
+		MainModuleUpgradable: wc.MainModuleUpgradableAddress.Hex(),
+		GuestModule:          wc.GuestModuleAddress.Hex(),
+		Utils:                wc.UtilsAddress.Hex(),
+	}
+}
+
+type ReceiptFeeRecord struct {
+	Value  string `json:"value"`
+	Symbol string `json:"symbol"`
+	Token  string `json:"token,omitempty"`
+}
+
+// ReceiptStore persists receipt records.
+type ReceiptStore interface {
+	Put(ctx context.Context, record ReceiptRecord) error
+}
+
+// ReceiptHistory is implemented by receipt stores that can list what they
+// hold.
+type ReceiptHistory interface {
+	// Recent returns the last n records, oldest first.
+	Recent(ctx context.Context, n int) (*ReceiptPage, error)
+}
+
+// ReceiptPage is a slice of a store's records, oldest first.
+type ReceiptPage struct {
+	Records []ReceiptRecord
+	// Total is how many records the store holds.
+	Total int
+	// Skipped counts stored entries that couldn't be decoded.
+	Skipped int
+}
+
+// defaultReceiptDBPath is the SQLite store's database when no path is set.
+const defaultReceiptDBPath = "receipts.db"
+
+// errSQLiteNotBuilt is returned for the sqlite receipt store by a binary
+// built without the sqlite tag.
+var errSQLiteNotBuilt = errors.New("the sqlite receipt store is not built in; rebuild with -tags sqlite or use the file store")
+
+// NewReceiptStore returns the configured receipt store, or nil when receipt
+// storage is disabled. Stores that hold resources, like the SQLite one,
+// also implement io.Closer. The SQLite store is only available in binaries
+// built with the sqlite tag; otherwise it fails with errSQLiteNotBuilt.
+func NewReceiptStore(ctx context.Context, cfg *ReceiptStoreConfig) (ReceiptStore, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	switch cfg.Type {
+	case "", "file":
+		return &fileReceiptStore{path: cfg.Path}, nil
+	case "sqlite":
+		return openSQLiteReceiptStore(ctx, cmp.Or(cfg.Path, defaultReceiptDBPath))
+	case "s3":
+		return newS3ReceiptStore(cfg)
+	default:
+		return nil, fmt.Errorf("unknown receipt store type %q", cfg.Type)
+	}
+}
+
+// StoreReceipts writes a record for every result that produced a receipt,
+// and returns the writes that failed. A failure shouldn't fail the run, since
+// the transactions themselves have already been relayed. Pass a
+// ReceiptWriter to return without waiting for the writes.
+func StoreReceipts(ctx context.Context, store ReceiptStore, results []TxResult, chainID int64, walletAddr common.Address, wc sequence.WalletContext, label string) error {
+	contextRecord := newWalletContextRecord(wc)
+	var errs []error
+
+	for _, r := range results {
+		if r.Receipt == nil {
+			continue
+		}
+
+		record := ReceiptRecord{
+			OpHash:        r.MetaTxnID.String(),
+			TxHash:        r.TxHash,
+			Status:        "succeeded",
+			GasUsed:       r.Receipt.GasUsed,
+			ChainID:       chainID,
+			Wallet:        walletAddr.Hex(),
+			WalletContext: contextRecord,
+			Label:         label,
+			Timestamp:     time.Now().UTC(),
+		}
+		if r.Confirmation > 0 {
+			record.ConfirmationSeconds = r.Confirmation.Round(time.Millisecond).Seconds()
+		}
+		if r.Receipt.Status != types.ReceiptStatusSuccessful {
+			record.Status = "failed"
+		} else if _, isPartial := partialCalls(r.Calls); isPartial {
+			record.Status = "partial"
+		}
+		record.Calls = r.Calls
+		record.CumulativeGasUsed = r.Receipt.CumulativeGasUsed
+		if cost := receiptGasCost(r.Receipt); cost != nil {
+			record.EffectiveGasPrice = r.Receipt.EffectiveGasPrice.String()
+			record.GasCost = cost.String()
+		}
+		if r.Fee != nil {
+			record.Fee = &ReceiptFeeRecord{
+				Value:  feeOptionValue(r.Fee).String(),
+				Symbol: r.Fee.Token.Symbol,
+			}
+			if !isNativeFeeOption(r.Fee) {
+				record.Fee.Token = r.Fee.Token.ContractAddress.Hex()
+			}
+		}
+
+		if err := store.Put(ctx, record); err != nil {
+			errs = append(errs, fmt.Errorf("store receipt for %s: %w", record.OpHash, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// fileReceiptStore appends records as JSON lines to a local file.
+type fileReceiptStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+func (s *fileReceiptStore) Put(ctx context.Context, record ReceiptRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Recent reads the whole file and returns its last n records. Lines that
+// don't parse are counted and skipped, so a truncated final write doesn't
+// hide the rest of the history.
+func (s *fileReceiptStore) Recent(ctx context.Context, n int) (*ReceiptPage, error) {
+	s.mu.Lock()
+	data, err := os.ReadFile(s.path)
+	s.mu.Unlock()
+	if errors.Is(err, os.ErrNotExist) {
+		return &ReceiptPage{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	page := &ReceiptPage{}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		var record ReceiptRecord
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			page.Skipped++
+			continue
+		}
+		page.Records = append(page.Records, record)
+	}
+	page.Total = len(page.Records)
+	if page.Total > n {
+		page.Records = page.Records[page.Total-n:]
+	}
+	return page, nil
+}
+
+// DefaultHistoryCount is how many records the history subcommand lists when
+// no count is given.
+const DefaultHistoryCount = 20
+
+// PrintReceiptHistory writes the last n records of the configured receipt
+// store to w, oldest first. The S3 store can't be listed.
+func PrintReceiptHistory(ctx context.Context, w io.Writer, cfg *ReceiptStoreConfig, n int) error {
+	if cfg == nil {
+		return errors.New("receiptStore is not configured")
+	}
+	store, err := NewReceiptStore(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	if closer, ok := store.(io.Closer); ok {
+		defer closer.Close()
+	}
+	history, ok := store.(ReceiptHistory)
+	if !ok {
+		return fmt.Errorf("the %s receipt store can't be listed; query it directly", cfg.Type)
+	}
+	page, err := history.Recent(ctx, n)
+	if err != nil {
+		return fmt.Errorf("read receipt history: %w", err)
+	}
+
+	source := cfg.Path
+	if cfg.Type == "sqlite" {
+		source = cmp.Or(source, defaultReceiptDBPath)
+	}
+	if page.Total == 0 && page.Skipped == 0 {
+		fmt.Fprintf(w, "No receipt records yet in %s.\n", source)
+		return nil
+	}
+	fmt.Fprintf(w, "--- Receipt history (%d of %d records in %s) ---\n", len(page.Records), page.Total, source)
+	fmt.Fprintf(w, "%-20s %-8s %-9s %-66s %-66s %-42s %s\n", "Time (UTC)", "Chain", "Status", "Op Hash", "Tx Hash", "Wallet", "Fee")
+	for _, record := range page.Records {
+		fee := "-"
+		if record.Fee != nil {
+			fee = record.Fee.Value + " " + record.Fee.Symbol
+		}
+		if record.Label != "" {
+			fee += " [" + record.Label + "]"
+		}
+		fmt.Fprintf(w, "%-20s %-8d %-9s %-66s %-66s %-42s %s\n", record.Timestamp.UTC().Format(time.DateTime), record.ChainID, record.Status, record.OpHash, record.TxHash, record.Wallet, fee)
+	}
+	if page.Skipped > 0 {
+		fmt.Fprintf(w, "Skipped %d unreadable record(s).\n", page.Skipped)
+	}
+	return nil
+}
+
+// s3ReceiptStore writes each record as a JSON object to an S3 bucket, keyed
+// by op hash. Requests are signed with AWS Signature Version 4 using the
+// standard AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and (optional)
+// AWS_SESSION_TOKEN environment variables.
+type s3ReceiptStore struct {
+	bucket   string
+	region   string
+	prefix   string
+	endpoint string
+
+	accessKey    string
+	secretKey    string
+	sessionToken string
+
+	client *http.Client
+}
+
+func newS3ReceiptStore(cfg *ReceiptStoreConfig) (*s3ReceiptStore, error) {
+	store := &s3ReceiptStore{
+		bucket:       cfg.Bucket,
+		region:       cfg.Region,
+		prefix:       cfg.Prefix,
+		endpoint:     strings.TrimSuffix(cfg.Endpoint, "/"),
+		accessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		client:       &http.Client{Timeout: 30 * time.Second},
+	}
+	if store.accessKey == "" || store.secretKey == "" {
+		return nil, errors.New("s3 receipt store requires AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+	return store, nil
+}
+
+func (s *s3ReceiptStore) Put(ctx context.Context, record ReceiptRecord) error {
+	body, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	key := s.prefix + record.OpHash + ".json"
+
+	// Virtual-hosted style on AWS, path style for custom endpoints.
+	var url, host, path string
+	if s.endpoint != "" {
+		url = s.endpoint + "/" + s.bucket + "/" + key
+		path = "/" + s.bucket + "/" + key
+	} else {
+		host = fmt.Sprintf("%s.s3.%s.amazonaws.com", s.bucket, s.region)
+		url = "https://" + host + "/" + key
+		path = "/" + key
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if host == "" {
+		host = req.URL.Host
+	}
+	req.Header.Set("Content-Type", "application/json")
+	s.sign(req, host, path, body, time.Now().UTC())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("s3 put %s: %s: %s", key, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// sign adds AWS Signature Version 4 headers to req.
+func (s *s3ReceiptStore) sign(req *http.Request, host, path string, body []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	payloadHash := sha256Hex(body)
+
+	headers := map[string]string{
+		"host":                 host,
+		"x-amz-content-sha256": payloadHash,
+		"x-amz-date":           amzDate,
+	}
+	if contentType := req.Header.Get("Content-Type"); contentType != "" {
+		headers["content-type"] = contentType
+	}
+	if s.sessionToken != "" {
+		headers["x-amz-security-token"] = s.sessionToken
+	}
+
+	canonicalRequest, signedHeaders := awsCanonicalRequest(req.Method, path, headers, payloadHash)
+	scope, signature := awsSignature(s.secretKey, amzDate, s.region, "s3", canonicalRequest)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if s.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.sessionToken)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, scope, signedHeaders, signature))
+}
+
+// awsCanonicalRequest returns the SigV4 canonical request for a request
+// without a query string, and the list of signed headers. headers maps
+// lowercase names to values; all of them are signed.
+func awsCanonicalRequest(method, path string, headers map[string]string, payloadHash string) (request, signedHeaders string) {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	signedHeaders = strings.Join(names, ";")
+
+	request = strings.Join([]string{
+		method,
+		awsURIEncodePath(path),
+		"",
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	return request, signedHeaders
+}
+
+// awsSignature signs canonicalRequest for service in region at amzDate
+// (in the 20060102T150405Z form), returning the credential scope and the
+// hex signature.
+func awsSignature(secretKey, amzDate, region, service, canonicalRequest string) (scope, signature string) {
+	date := amzDate[:len("20060102")]
+	scope = date + "/" + region + "/" + service + "/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	signingKey := hmacSHA256([]byte("AWS4"+secretKey), date)
+	signingKey = hmacSHA256(signingKey, region)
+	signingKey = hmacSHA256(signingKey, service)
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	return scope, hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+}
+
+// awsURIEncodePath percent-encodes every byte of path except unreserved
+// characters and '/', as SigV4 requires.
+func awsURIEncodePath(path string) string {
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' || c == '/' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// SQLReceiptStore keeps records in a receipts table of a SQL database. Each
+// row holds the record's summary fields as columns, for querying, and the
+// whole record as JSON.
+type SQLReceiptStore struct {
+	db *sql.DB
+}
+
+// receiptTimeFormat is fixed width, so timestamps stored as text sort
+// chronologically.
+const receiptTimeFormat = "2006-01-02T15:04:05.000000000Z"
+
+const createReceiptsTable = `CREATE TABLE IF NOT EXISTS receipts (
+	timestamp  TEXT NOT NULL,
+	chain_id   INTEGER NOT NULL,
+	wallet     TEXT NOT NULL,
+	op_hash    TEXT NOT NULL,
+	tx_hash    TEXT NOT NULL,
+	status     TEXT NOT NULL,
+	fee_value  TEXT,
+	fee_symbol TEXT,
+	fee_token  TEXT,
+	label      TEXT,
+	record     TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS receipts_timestamp ON receipts (timestamp);
+CREATE INDEX IF NOT EXISTS receipts_op_hash ON receipts (op_hash)`
+
+// NewSQLReceiptStore stores records in db, creating the receipts table if
+// it doesn't exist. The statements are written for SQLite; other databases
+// need to accept the same SQL, including ? placeholders. Closing the store
+// closes db.
+func NewSQLReceiptStore(ctx context.Context, db *sql.DB) (*SQLReceiptStore, error) {
+	if _, err := db.ExecContext(ctx, createReceiptsTable); err != nil {
+		return nil, fmt.Errorf("create receipts table: %w", err)
+	}
+	return &SQLReceiptStore{db: db}, nil
+}
+
+func (s *SQLReceiptStore) Put(ctx context.Context, record ReceiptRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	var feeValue, feeSymbol, feeToken sql.NullString
+	if record.Fee != nil {
+		feeValue = sql.NullString{String: record.Fee.Value, Valid: true}
+		feeSymbol = sql.NullString{String: record.Fee.Symbol, Valid: true}
+		feeToken = sql.NullString{String: record.Fee.Token, Valid: record.Fee.Token != ""}
+	}
+	_, err = s.db.ExecContext(ctx,
+		`INSERT INTO receipts (timestamp, chain_id, wallet, op_hash, tx_hash, status, fee_value, fee_symbol, fee_token, label, record)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		record.Timestamp.UTC().Format(receiptTimeFormat), record.ChainID, record.Wallet, record.OpHash, record.TxHash, record.Status,
+		feeValue, feeSymbol, feeToken, sql.NullString{String: record.Label, Valid: record.Label != ""}, string(data))
+	return err
+}
+
+// Recent returns the last n records by timestamp.
+func (s *SQLReceiptStore) Recent(ctx context.Context, n int) (*ReceiptPage, error) {
+	page := &ReceiptPage{}
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM receipts`).Scan(&page.Total); err != nil {
+		return nil, err
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT record FROM receipts ORDER BY timestamp DESC LIMIT ?`, n)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var record ReceiptRecord
+		if err := json.Unmarshal([]byte(data), &record); err != nil {
+			page.Skipped++
+			continue
+		}
+		page.Records = append(page.Records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	slices.Reverse(page.Records)
+	return page, nil
+}
+
+func (s *SQLReceiptStore) Close() error {
+	return s.db.Close()
+}
+
+// defaultReceiptBufferSize is how many records a ReceiptWriter queues when
+// receiptStore.bufferSize is unset.
+const defaultReceiptBufferSize = 256
+
+// errReceiptBufferFull is returned by ReceiptWriter.Put for a record dropped
+// because the queue was full.
+var errReceiptBufferFull = errors.New("receipt buffer full; record dropped")
+
+// ReceiptWriter writes records to a ReceiptStore from a background
+// goroutine, so a slow or failing store never holds up relaying. Put only
+// queues the record; write failures are returned by Close, which must be
+// called to flush the queue. Put must not be called after Close.
+type ReceiptWriter struct {
+	store ReceiptStore
+	queue chan ReceiptRecord
+	done  chan struct{}
+	// unwritten counts the records queued but not yet written or failed.
+	unwritten atomic.Int64
+	// abandoned is set when Close gives up; queued records are then
+	// dropped instead of written to the closed store.
+	abandoned atomic.Bool
+	// errs holds the write failures; it is only read after done closes.
+	errs []error
+}
+
+// NewReceiptWriter starts a writer that queues up to size records for
+// store, or defaultReceiptBufferSize when size is 0.
+func NewReceiptWriter(store ReceiptStore, size int) *ReceiptWriter {
+	w := &ReceiptWriter{
+		store: store,
+		queue: make(chan ReceiptRecord, cmp.Or(size, defaultReceiptBufferSize)),
+		done:  make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+func (w *ReceiptWriter) run() {
+	defer close(w.done)
+	for record := range w.queue {
+		if w.abandoned.Load() {
+			continue
+		}
+		// The caller may have moved on; its context doesn't bound the write.
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		if err := w.store.Put(ctx, record); err != nil {
+			w.errs = append(w.errs, fmt.Errorf("store receipt for %s: %w", record.OpHash, err))
+		}
+		cancel()
+		w.unwritten.Add(-1)
+	}
+}
+
+// Put queues record without blocking, or drops it with errReceiptBufferFull
+// when the queue is full.
+func (w *ReceiptWriter) Put(ctx context.Context, record ReceiptRecord) error {
+	w.unwritten.Add(1)
+	select {
+	case w.queue <- record:
+		return nil
+	default:
+		w.unwritten.Add(-1)
+		return errReceiptBufferFull
+	}
+}
+
+// Close waits for the queued records to be written, or for ctx to be done,
+// then closes the store if it is an io.Closer. It returns every write that
+// failed and, when ctx ends the wait, how many records were dropped
+// unwritten. The store is closed either way.
+func (w *ReceiptWriter) Close(ctx context.Context) error {
+	close(w.queue)
+	var errs []error
+	select {
+	case <-w.done:
+		errs = w.errs
+	case <-ctx.Done():
+		w.abandoned.Store(true)
+		errs = append(errs, fmt.Errorf("%d receipt record(s) dropped unwritten: %w", w.unwritten.Load(), ctx.Err()))
+	}
+	if closer, ok := w.store.(io.Closer); ok {
+		errs = append(errs, closer.Close())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,14 @@
+//go:build !sqlite
+
+package txrunner
+
+import "context"
+
+// sqliteReceiptStoreBuilt reports whether the sqlite receipt store is
+// built in. The SQLite driver is large, so it is left out unless the
+// sqlite tag is set.
+const sqliteReceiptStoreBuilt = false
+
+func openSQLiteReceiptStore(ctx context.Context, path string) (*SQLReceiptStore, error) {
+	return nil, errSQLiteNotBuilt
+}
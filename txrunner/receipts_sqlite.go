@@ -0,0 +1,32 @@
+//go:build sqlite
+
+package txrunner
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqliteReceiptStoreBuilt reports whether the sqlite receipt store is
+// built in.
+const sqliteReceiptStoreBuilt = true
+
+// openSQLiteReceiptStore opens, creating if needed, the SQLite database at
+// path.
+func openSQLiteReceiptStore(ctx context.Context, path string) (*SQLReceiptStore, error) {
+	// Wait out a concurrent run's write lock rather than failing with
+	// SQLITE_BUSY.
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("open receipt database %s: %w", path, err)
+	}
+	store, err := NewSQLReceiptStore(ctx, db)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("open receipt database %s: %w", path, err)
+	}
+	return store, nil
+}
@@ -0,0 +1,65 @@
+//go:build sqlite
+
+package txrunner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSQLiteReceiptStoreRecent(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "receipts.db")
+	store, err := NewReceiptStore(ctx, &ReceiptStoreConfig{Type: "sqlite", Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	var want []ReceiptRecord
+	// Stored out of order, to check that history sorts by time.
+	for _, i := range []int{1, 0, 2} {
+		record := ReceiptRecord{
+			OpHash:    fmt.Sprintf("0x%064x", i),
+			TxHash:    fmt.Sprintf("0x%064x", 100+i),
+			Status:    "succeeded",
+			ChainID:   421614,
+			Wallet:    "0x2c7536E3605D9C16a7a3D7b1898e529396a65c23",
+			Fee:       &ReceiptFeeRecord{Value: "10", Symbol: "USDC", Token: "0x00000000000000000000000000000000000000c0"},
+			Calls:     []CallOutcome{{Index: 0, Status: "succeeded"}},
+			Timestamp: base.Add(time.Duration(i) * time.Second),
+		}
+		if err := store.Put(ctx, record); err != nil {
+			t.Fatal(err)
+		}
+		if i > 0 {
+			want = append(want, record)
+		}
+	}
+	slices.SortFunc(want, func(a, b ReceiptRecord) int { return a.Timestamp.Compare(b.Timestamp) })
+	if err := store.(io.Closer).Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reopening finds the records already there.
+	store, err = NewReceiptStore(ctx, &ReceiptStoreConfig{Type: "sqlite", Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.(io.Closer).Close()
+	page, err := store.(ReceiptHistory).Recent(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 3 || page.Skipped != 0 {
+		t.Errorf("total %d, skipped %d; want 3, 0", page.Total, page.Skipped)
+	}
+	if !reflect.DeepEqual(page.Records, want) {
+		t.Errorf("got %+v\nwant %+v", page.Records, want)
+	}
+}
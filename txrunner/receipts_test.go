@@ -0,0 +1,190 @@
+package txrunner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockingStore is a ReceiptStore whose writes wait for release.
+type blockingStore struct {
+	release chan struct{}
+	mu      sync.Mutex
+	stored  []string
+	closed  bool
+}
+
+func (s *blockingStore) Put(ctx context.Context, record ReceiptRecord) error {
+	<-s.release
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stored = append(s.stored, record.OpHash)
+	return nil
+}
+
+func (s *blockingStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+func TestReceiptWriterDoesNotBlock(t *testing.T) {
+	store := &blockingStore{release: make(chan struct{})}
+	w := NewReceiptWriter(store, 2)
+
+	// The writer takes the first record and blocks on it; two more fill
+	// the queue and the fourth is dropped, all without waiting.
+	start := time.Now()
+	var dropped []string
+	for _, op := range []string{"a", "b", "c", "d"} {
+		for op == "b" && len(w.queue) > 0 {
+			time.Sleep(time.Millisecond) // let the writer take "a"
+		}
+		if err := w.Put(context.Background(), ReceiptRecord{OpHash: op}); errors.Is(err, errReceiptBufferFull) {
+			dropped = append(dropped, op)
+		} else if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Put blocked for %v", elapsed)
+	}
+	if !slices.Equal(dropped, []string{"d"}) {
+		t.Errorf("dropped %q, want [d]", dropped)
+	}
+
+	// Close gives up when the store doesn't finish in time, reports the
+	// records left unwritten, and still closes the store.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := w.Close(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("close with a stuck store: got %v, want a deadline error", err)
+	} else if want := "3 receipt record(s) dropped"; !strings.Contains(err.Error(), want) {
+		t.Errorf("close with a stuck store: got %q, want it to contain %q", err, want)
+	}
+	store.mu.Lock()
+	closed := store.closed
+	store.mu.Unlock()
+	if !closed {
+		t.Error("close with a stuck store left the store open")
+	}
+	// The write in flight finishes; the queued records are not written.
+	close(store.release)
+	<-w.done
+	if !slices.Equal(store.stored, []string{"a"}) {
+		t.Errorf("stored %q, want [a]", store.stored)
+	}
+}
+
+func TestFileReceiptStoreIsDefault(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "receipts.jsonl")
+	store, err := NewReceiptStore(ctx, &ReceiptStoreConfig{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := ReceiptRecord{OpHash: fmt.Sprintf("0x%064x", 1), Status: "succeeded", ChainID: 421614}
+	if err := store.Put(ctx, record); err != nil {
+		t.Fatal(err)
+	}
+	page, err := store.(ReceiptHistory).Recent(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 1 || page.Records[0].OpHash != record.OpHash {
+		t.Errorf("got %+v, want the one record stored", page)
+	}
+}
+
+func TestReceiptWriterCloseFlushes(t *testing.T) {
+	store := &blockingStore{release: make(chan struct{})}
+	close(store.release)
+	w := NewReceiptWriter(store, 0)
+	for i := range 10 {
+		if err := w.Put(context.Background(), ReceiptRecord{OpHash: strconv.Itoa(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.stored) != 10 || !store.closed {
+		t.Errorf("stored %d records, closed %v; want 10, true", len(store.stored), store.closed)
+	}
+}
+
+// The SigV4 vectors are AWS's published examples: get-vanilla from the
+// Signature Version 4 test suite, and the GET Object example from the S3
+// API reference ("Signature Calculations for the Authorization Header").
+func TestAWSSignatureMatchesPublishedVectors(t *testing.T) {
+	emptyHash := sha256Hex(nil)
+	tests := []struct {
+		name                     string
+		secretKey                string
+		region, service, amzDate string
+		method, path             string
+		headers                  map[string]string
+		wantSignedHeaders        string
+		wantRequestHash          string
+		wantScope                string
+		wantSignature            string
+	}{
+		{
+			name:      "get-vanilla",
+			secretKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+			region:    "us-east-1", service: "service", amzDate: "20150830T123600Z",
+			method: "GET", path: "/",
+			headers: map[string]string{
+				"host":       "example.amazonaws.com",
+				"x-amz-date": "20150830T123600Z",
+			},
+			wantSignedHeaders: "host;x-amz-date",
+			wantRequestHash:   "bb579772317eb040ac9ed261061d46c1f17a8133879d6129b6e1c25292927e63",
+			wantScope:         "20150830/us-east-1/service/aws4_request",
+			wantSignature:     "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		},
+		{
+			name:      "s3 get object",
+			secretKey: "wJalrXUtnFEMI/K7MDENG/bPxRfiCYEXAMPLEKEY",
+			region:    "us-east-1", service: "s3", amzDate: "20130524T000000Z",
+			method: "GET", path: "/test.txt",
+			headers: map[string]string{
+				"host":                 "examplebucket.s3.amazonaws.com",
+				"range":                "bytes=0-9",
+				"x-amz-content-sha256": emptyHash,
+				"x-amz-date":           "20130524T000000Z",
+			},
+			wantSignedHeaders: "host;range;x-amz-content-sha256;x-amz-date",
+			wantRequestHash:   "7344ae5b7ee6c3e7e6b0fe0640412a37625d1fbfff95c48bbb2dc43964946972",
+			wantScope:         "20130524/us-east-1/s3/aws4_request",
+			wantSignature:     "f0e8bdb87c964420e857bd35b5d6ed310bd44f0170aba48dd91039c6036bdb41",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request, signedHeaders := awsCanonicalRequest(tt.method, tt.path, tt.headers, emptyHash)
+			if signedHeaders != tt.wantSignedHeaders {
+				t.Errorf("signed headers: got %q, want %q", signedHeaders, tt.wantSignedHeaders)
+			}
+			if got := sha256Hex([]byte(request)); got != tt.wantRequestHash {
+				t.Errorf("canonical request hash: got %s, want %s\ncanonical request:\n%s", got, tt.wantRequestHash, request)
+			}
+			scope, signature := awsSignature(tt.secretKey, tt.amzDate, tt.region, tt.service, request)
+			if scope != tt.wantScope {
+				t.Errorf("scope: got %q, want %q", scope, tt.wantScope)
+			}
+			if signature != tt.wantSignature {
+				t.Errorf("signature: got %s, want %s", signature, tt.wantSignature)
+			}
+		})
+	}
+}
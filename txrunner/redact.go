@@ -0,0 +1,71 @@
+package txrunner
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"sync"
+)
+
+// ---------------------------------------------------------------------------
+// Address redaction — masking addresses in text output for shared logs
+// ---------------------------------------------------------------------------
+
+// addressPattern matches a 0x-prefixed 20-byte address on its own, not the
+// start of a longer hex string such as a hash or calldata.
+var addressPattern = regexp.MustCompile(`\b0x[0-9a-fA-F]{40}\b`)
+
+// redactAddressText masks the middle of every address in s, keeping the
+// first and last four hex digits, e.g. 0x1234…abcd.
+func redactAddressText(s string) string {
+	return addressPattern.ReplaceAllStringFunc(s, func(addr string) string {
+		return addr[:6] + "…" + addr[len(addr)-4:]
+	})
+}
+
+// RedactWriter masks the middle of every address in the text written to
+// it, as redactAddressText does, before passing the text on to the
+// underlying writer. Text is held back until a line ends, so an address
+// split across writes is still masked; Flush writes out an unfinished last
+// line. It is safe for concurrent use.
+type RedactWriter struct {
+	w   io.Writer
+	mu  sync.Mutex
+	buf []byte
+}
+
+// NewRedactWriter returns a RedactWriter that writes to w.
+func NewRedactWriter(w io.Writer) *RedactWriter {
+	return &RedactWriter{w: w}
+}
+
+func (rw *RedactWriter) Write(p []byte) (int, error) {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+
+	rw.buf = append(rw.buf, p...)
+	end := bytes.LastIndexByte(rw.buf, '\n') + 1
+	if end == 0 {
+		return len(p), nil
+	}
+	lines := redactAddressText(string(rw.buf[:end]))
+	rw.buf = append(rw.buf[:0], rw.buf[end:]...)
+	if _, err := io.WriteString(rw.w, lines); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush writes out text held back for lack of a final newline.
+func (rw *RedactWriter) Flush() error {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+
+	if len(rw.buf) == 0 {
+		return nil
+	}
+	rest := redactAddressText(string(rw.buf))
+	rw.buf = rw.buf[:0]
+	_, err := io.WriteString(rw.w, rest)
+	return err
+}
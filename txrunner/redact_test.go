@@ -0,0 +1,31 @@
+package txrunner
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRedactWriterMasksAddressesSplitAcrossWrites(t *testing.T) {
+	var b strings.Builder
+	rw := NewRedactWriter(&b)
+
+	addr := "0x2c7536E3605D9C16a7a3D7b1898e529396a65c23"
+	hash := "0x1275af0d2e69f989baf92a2ca49b468bdb267d7dae1bc02f3294ca44f45b0d2a"
+	for _, chunk := range []string{"Wallet: " + addr[:10], addr[10:] + "\nHash: ", hash + "\nTo: " + addr} {
+		if _, err := rw.Write([]byte(chunk)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := "Wallet: 0x2c75…5c23\nHash: " + hash + "\n"
+	if got := b.String(); got != want {
+		t.Fatalf("before flush got %q, want %q", got, want)
+	}
+
+	if err := rw.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	want += "To: 0x2c75…5c23"
+	if got := b.String(); got != want {
+		t.Fatalf("after flush got %q, want %q", got, want)
+	}
+}
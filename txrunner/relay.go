@@ -0,0 +1,628 @@
+package txrunner
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/0xsequence/ethkit/ethrpc"
+	"github.com/0xsequence/ethkit/ethtxn"
+	"github.com/0xsequence/ethkit/go-ethereum"
+	"github.com/0xsequence/ethkit/go-ethereum/common"
+	"github.com/0xsequence/ethkit/go-ethereum/common/hexutil"
+	"github.com/0xsequence/ethkit/go-ethereum/core/types"
+	sequence "github.com/0xsequence/go-sequence"
+	"github.com/0xsequence/go-sequence/lib/simulator"
+)
+
+// ---------------------------------------------------------------------------
+// Transaction helpers — fee handling, signing, and relay
+// ---------------------------------------------------------------------------
+
+// RelayedBundle is a meta-transaction bundle accepted by the relayer.
+type RelayedBundle struct {
+	MetaTxnID   sequence.MetaTxnID
+	NativeTx    *types.Transaction
+	WaitReceipt ethtxn.WaitReceipt
+	// Fee is the fee option paid in the bundle, or nil if none was required.
+	Fee *sequence.RelayerFeeOption
+	// Approvals are the allowances the bundle grants, e.g. to the
+	// feeAutoSwap router.
+	Approvals []Approval
+	// SubmittedAt is when the relayer accepted the bundle.
+	SubmittedAt time.Time
+	// Signed is the signed bundle of a dry run, which has no relay fields
+	// set since it was never sent.
+	Signed *sequence.SignedTransactions
+}
+
+// SendTransactionsWithFees attaches a fee payment (if required by the relayer),
+// signs the meta-transaction bundle, and sends it through the relayer. When
+// progress is non-nil it receives FeeSelected, Signed and Submitted events;
+// see emitProgress for the delivery guarantees.
+func (r *Runner) SendTransactionsWithFees(ctx context.Context, opts SendOptions, txs sequence.Transactions, progress chan<- ProgressEvent) (*RelayedBundle, error) {
+	out := r.out()
+	if opts.Dedupe {
+		var removed int
+		txs, removed = dedupeTransactions(txs)
+		if removed > 0 {
+			fmt.Fprintf(out, "Removed %d duplicate transaction(s) from the bundle\n", removed)
+		}
+	}
+
+	txsWithFee, feeQuote, fee, err := r.MaybeAttachFeePayment(ctx, opts, txs)
+	if err != nil && (errors.Is(err, errFetchFeeOptions) || errors.Is(err, errNoAffordableFeeOption)) {
+		// Options quoted by one relayer are only valid with it, so the
+		// fallback that quotes the bundle also signs and relays it.
+		fallbackOpts := opts
+		fallbackOpts.FeeQuote = nil
+		for _, fallback := range opts.FallbackRelayers {
+			fmt.Fprintf(out, "Fee discovery failed (%v); asking fallback relayer %s\n", err, fallback.URL)
+			fallbackRunner := r.withWallet(fallback.Wallet)
+			txsWithFee, feeQuote, fee, err = fallbackRunner.MaybeAttachFeePayment(ctx, fallbackOpts, txs)
+			if err == nil {
+				fmt.Fprintf(out, "Using fee options from fallback relayer %s\n", fallback.URL)
+				r = fallbackRunner
+				break
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	emitProgress(progress, ProgressEvent{Kind: ProgressFeeSelected, Fee: fee})
+
+	cfg, wallet := r.Config, r.Wallet
+
+	maxValue := cfg.maxTxValue()
+	for i, txn := range txsWithFee {
+		if err := validateGasLimit(txn.GasLimit); err != nil {
+			return nil, fmt.Errorf("transaction %d: %w", i, err)
+		}
+		if maxValue != nil && txn.Value != nil && txn.Value.Cmp(maxValue) > 0 {
+			return nil, fmt.Errorf("transaction %d: value %s exceeds maxTxValue %s", i, cfg.NumberFormat.Native(txn.Value), cfg.NumberFormat.Native(maxValue))
+		}
+		if txn.To == wallet.Address() {
+			if err := validateSelfCall(txn); err != nil {
+				return nil, fmt.Errorf("transaction %d: %w", i, err)
+			}
+			fmt.Fprintf(out, "Warning: transaction %d calls the wallet itself and will modify it\n", i)
+		}
+	}
+	if !cfg.SkipBlockGasLimitCheck {
+		if err := r.checkBundleGas(ctx, txsWithFee); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.BundleText != nil {
+		opts.BundleText.add(opts.BundleOrder, opts.BundleLabel, txsWithFee)
+	}
+
+	signed, err := wallet.SignTransactions(ctx, txsWithFee)
+	if err != nil {
+		return nil, fmt.Errorf("sign transaction: %w", err)
+	}
+	emitProgress(progress, ProgressEvent{Kind: ProgressSigned, Digest: signed.Digest})
+
+	if opts.DryRun {
+		metaTxnID, err := printDryRunBundle(out, cfg, signed, fee)
+		if err != nil {
+			return nil, err
+		}
+		return &RelayedBundle{MetaTxnID: metaTxnID, Fee: fee, Signed: signed}, nil
+	}
+
+	send := func() (sequence.MetaTxnID, *types.Transaction, ethtxn.WaitReceipt, error) {
+		if feeQuote != nil {
+			return wallet.SendTransactions(ctx, signed, feeQuote)
+		}
+		return wallet.SendTransactions(ctx, signed)
+	}
+	metaTxnID, nativeTx, waitReceipt, err := send()
+	if err != nil && cfg.RetryUndeployed && isWalletNotDeployedError(err) {
+		fmt.Fprintf(out, "Relayer rejected the bundle because wallet %s is not deployed: %v\n", wallet.Address().Hex(), err)
+		if werr := r.awaitWalletDeployment(ctx); werr != nil {
+			return nil, fmt.Errorf("%w (not retried: %v)", err, werr)
+		}
+		fmt.Fprintln(out, "Wallet is deployed; retrying the relay once...")
+		metaTxnID, nativeTx, waitReceipt, err = send()
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Without a usable op hash there is nothing to wait on, so the bundle
+	// may or may not have been submitted.
+	if err := validateMetaTxnID(metaTxnID); err != nil {
+		return nil, err
+	}
+
+	// The relayed bundle spends from the wallet, so cached balances are stale.
+	r.balances.invalidate(wallet.Address())
+	emitProgress(progress, ProgressEvent{Kind: ProgressSubmitted, MetaTxnID: metaTxnID})
+
+	return &RelayedBundle{
+		MetaTxnID:   metaTxnID,
+		NativeTx:    nativeTx,
+		WaitReceipt: waitReceipt,
+		Fee:         fee,
+		Approvals:   bundleApprovals(txsWithFee),
+		SubmittedAt: time.Now(),
+	}, nil
+}
+
+// printDryRunBundle prints what a dry run would have relayed for signed:
+// its op hash (the ID the relayer would return), the fee option paid, and
+// the signed execute calldata. It returns the op hash.
+func printDryRunBundle(out io.Writer, cfg *Config, signed *sequence.SignedTransactions, fee *sequence.RelayerFeeOption) (sequence.MetaTxnID, error) {
+	payload, err := signed.Payload()
+	if err != nil {
+		return "", fmt.Errorf("dry run payload: %w", err)
+	}
+	metaTxnID, _, err := sequence.ComputeMetaTxnIDFromCallsPayload(&payload)
+	if err != nil {
+		return "", fmt.Errorf("dry run op hash: %w", err)
+	}
+	calldata, err := signed.ExecuteV3()
+	if err != nil {
+		return "", fmt.Errorf("dry run execute calldata: %w", err)
+	}
+
+	feeText := "none"
+	if fee != nil {
+		feeText = cfg.NumberFormat.fee(fee)
+	}
+	fmt.Fprintln(out, "Dry run: bundle signed, not relayed")
+	fmt.Fprintf(out, "  Op Hash:   %s\n", metaTxnID)
+	fmt.Fprintf(out, "  Fee:       %s\n", feeText)
+	fmt.Fprintf(out, "  Nonce:     %s (space %s)\n", signed.Nonce, signed.Space)
+	fmt.Fprintf(out, "  Signature: %s\n", hexutil.Encode(signed.Signature))
+	fmt.Fprintf(out, "  Calldata:  %s (execute on %s)\n", hexutil.Encode(calldata), signed.WalletAddress.Hex())
+	return metaTxnID, nil
+}
+
+// errBundleExceedsBlockGas is returned when a bundle needs more gas than
+// fits in a block, so no relayer can include it.
+var errBundleExceedsBlockGas = errors.New("bundle exceeds block gas limit")
+
+// checkBundleGas compares the gas txs need with the latest block's gas
+// limit. Pinned calls count their gas limit. Calls with the auto gas limit
+// are sized by the relayer, whose fee quote carries no estimate, so they only
+// count when SimulateBundleGas is set: then they count the gas they use when
+// the bundle is simulated from the wallet (see simulatePostBalances). If the
+// simulation fails, only the pinned limits are compared.
+func (r *Runner) checkBundleGas(ctx context.Context, txs sequence.Transactions) error {
+	cfg, out := r.Config, r.out()
+	need, auto := new(big.Int), false
+	for _, txn := range txs {
+		if isAutoGasLimit(txn.GasLimit) {
+			auto = true
+		} else {
+			need.Add(need, txn.GasLimit)
+		}
+	}
+	what := "pinned call gas limits"
+	if auto && cfg.SimulateBundleGas {
+		estimated, err := estimateAutoGas(ctx, cfg, r.Provider, r.Wallet.Address(), txs)
+		if err != nil {
+			fmt.Fprintf(out, "Warning: could not estimate the bundle's gas (%v); checking only pinned gas limits against the block gas limit\n", err)
+		} else {
+			need.Add(need, new(big.Int).SetUint64(estimated))
+			what = "pinned call gas limits and simulated gas"
+		}
+	}
+	if need.Sign() == 0 {
+		return nil
+	}
+
+	blockLimit, err := r.blockGasLimit.get(ctx, r.Provider)
+	if err != nil {
+		fmt.Fprintf(out, "Warning: could not read the block gas limit (%v); skipping the bundle gas check\n", err)
+		return nil
+	}
+	if need.Cmp(new(big.Int).SetUint64(blockLimit)) > 0 {
+		return fmt.Errorf("%w: %s total %s against a block gas limit of %d; split the calls across smaller bundles or lower their pinned gas limits", errBundleExceedsBlockGas, what, need, blockLimit)
+	}
+	return nil
+}
+
+// estimateAutoGas simulates txs from the wallet and returns the gas used by
+// the calls that carry the auto gas limit.
+func estimateAutoGas(ctx context.Context, cfg *Config, provider *ethrpc.Provider, walletAddr common.Address, txs sequence.Transactions) (uint64, error) {
+	payload, err := txs.Payload(walletAddr, big.NewInt(cfg.ChainID), nil, nil)
+	if err != nil {
+		return 0, fmt.Errorf("encode payload for simulation: %w", err)
+	}
+	results, err := simulator.SimulateV3(ctx, walletAddr, payload.Calls, provider)
+	if err != nil {
+		return 0, fmt.Errorf("simulate bundle: %w", err)
+	}
+	if len(results) != len(txs) {
+		return 0, fmt.Errorf("simulate bundle: got %d results for %d calls", len(results), len(txs))
+	}
+	var used uint64
+	for i, txn := range txs {
+		if isAutoGasLimit(txn.GasLimit) {
+			used += results[i].GasUsed
+		}
+	}
+	return used, nil
+}
+
+// blockGasLimitTTL is how long a block gas limit read is reused before the
+// latest block is read again.
+const blockGasLimitTTL = 10 * time.Minute
+
+// blockGasLimitCache holds the gas limit of the latest block on a runner's
+// provider, read on first use and again once it is blockGasLimitTTL old. It
+// is safe for concurrent use. A nil cache reads the limit every time.
+type blockGasLimitCache struct {
+	mu      sync.Mutex
+	limit   uint64
+	expires time.Time
+}
+
+func (c *blockGasLimitCache) get(ctx context.Context, provider *ethrpc.Provider) (uint64, error) {
+	if c == nil {
+		return latestBlockGasLimit(ctx, provider)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if time.Now().Before(c.expires) {
+		return c.limit, nil
+	}
+	limit, err := latestBlockGasLimit(ctx, provider)
+	if err != nil {
+		return 0, err
+	}
+	c.limit, c.expires = limit, time.Now().Add(blockGasLimitTTL)
+	return limit, nil
+}
+
+func latestBlockGasLimit(ctx context.Context, provider *ethrpc.Provider) (uint64, error) {
+	header, err := provider.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("fetch latest header: %w", err)
+	}
+	return header.GasLimit, nil
+}
+
+// errInvalidOpHash is returned when the relayer accepts a bundle but returns
+// an op hash that can't be tracked.
+var errInvalidOpHash = errors.New("relayer returned an invalid op hash")
+
+// validateMetaTxnID checks id is a 32-byte hex op hash, with or without a
+// 0x prefix.
+func validateMetaTxnID(id sequence.MetaTxnID) error {
+	s := strings.TrimPrefix(strings.TrimPrefix(id.String(), "0x"), "0X")
+	if s == "" {
+		return fmt.Errorf("%w: empty; the bundle's status is unknown, check the relayer before resending", errInvalidOpHash)
+	}
+	if b, err := hex.DecodeString(s); err != nil || len(b) != common.HashLength {
+		return fmt.Errorf("%w: %q is not a 32-byte hex hash; the bundle's status is unknown, check the relayer before resending", errInvalidOpHash, id.String())
+	}
+	return nil
+}
+
+// MaybeAttachFeePayment queries the relayer for fee options. If fees are required,
+// it picks the cheapest affordable option and adds a fee payment transaction,
+// first or last in the bundle according to cfg.FeePosition. If none is
+// affordable and FeeAutoSwap is configured, the swap into the fee token is
+// placed right ahead of the payment. The selected option is returned, or nil
+// when the relayer charges no fee.
+func (r *Runner) MaybeAttachFeePayment(ctx context.Context, opts SendOptions, txs sequence.Transactions) (sequence.Transactions, *sequence.RelayerFeeQuote, *sequence.RelayerFeeOption, error) {
+	out := r.out()
+	cfg, wallet, provider, balances := r.Config, r.Wallet, r.Provider, r.balances
+	position := cfg.feePosition()
+
+	var (
+		feeOptions []*sequence.RelayerFeeOption
+		feeQuote   *sequence.RelayerFeeQuote
+		err        error
+	)
+	if opts.FeeQuote != nil && opts.FeeQuote.Err == nil {
+		feeOptions, feeQuote = opts.FeeQuote.Options, opts.FeeQuote.Quote
+	} else {
+		feeOptions, feeQuote, err = wallet.FeeOptions(ctx, txs)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("%w: %w", errFetchFeeOptions, err)
+		}
+	}
+	resolveFeeTokenDecimals(ctx, out, cfg, provider, feeOptions)
+
+	// Native value attached to the calls must be covered alongside any fee.
+	callValue := transactionsValue(txs)
+
+	if len(feeOptions) == 0 {
+		if err := checkZeroFee(out, cfg, "the relayer quoted no fee options"); err != nil {
+			return nil, nil, nil, err
+		}
+		if callValue.Sign() > 0 {
+			balance, err := balances.nativeBalance(ctx, provider, wallet.Address())
+			if err != nil {
+				return nil, nil, nil, fmt.Errorf("native balance: %w", err)
+			}
+			if balance.Cmp(callValue) < 0 {
+				return nil, nil, nil, fmt.Errorf("wallet %s holds %s, needs %s for call value", wallet.Address().Hex(), cfg.NumberFormat.Native(balance), cfg.NumberFormat.Native(callValue))
+			}
+		}
+		return txs, feeQuote, nil, nil
+	}
+
+	// With the fee paid last, the calls may produce the fee tokens, so judge
+	// ERC-20 options by the balances left after simulating them.
+	var postBalances map[common.Address]*big.Int
+	if position == feePositionLast {
+		postBalances, err = simulatePostBalances(ctx, out, cfg, provider, wallet.Address(), txs, feeOptions)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	// When nothing is affordable outright, try swapping into a fee token.
+	var swapTxns sequence.Transactions
+	option, err := r.SelectFeeOption(ctx, feeOptions, callValue, postBalances)
+	if errors.Is(err, errNoAffordableFeeOption) && cfg.FeeAutoSwap != nil {
+		swap, swapErr := planFeeSwap(ctx, out, cfg, provider, balances, wallet.Address(), feeOptions, callValue)
+		if swapErr != nil {
+			return nil, nil, nil, fmt.Errorf("%w; fee auto-swap: %w", err, swapErr)
+		}
+		swapTxns, err = buildFeeSwapTransactions(cfg, wallet.Address(), swap)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		option = swap.Option
+		fmt.Fprintf(out, "Swapping up to %s of %s for %s %s via router %s to cover the relayer fee\n",
+			cfg.NumberFormat.amount(swap.AmountInMax, nil), cfg.FeeAutoSwap.FromToken,
+			cfg.NumberFormat.amount(swap.AmountOut, option.Token.Decimals), option.Token.Symbol, cfg.FeeAutoSwap.Router)
+	} else if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if feeOptionValue(option).Sign() == 0 {
+		if err := checkZeroFee(out, cfg, fmt.Sprintf("the selected %s fee option is zero-value", option.Token.Symbol)); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	feeTxn, err := buildFeePaymentTransaction(out, cfg, option)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	kind := "ERC-20"
+	if isNativeFeeOption(option) {
+		kind = "native"
+	}
+	fmt.Fprintf(out, "Including relayer fee payment of %s (%s) to %s\n", cfg.NumberFormat.fee(option), kind, option.To.Hex())
+	if !isNativeFeeOption(option) && !cfg.FeeTransferCheck {
+		if symbol, ok := nonRevertingTokens[cfg.ChainID][*option.Token.ContractAddress]; ok {
+			fmt.Fprintf(out, "Warning: fee token %s returns false instead of reverting on a failed transfer; set feeTransferCheck to verify the payment\n", symbol)
+		}
+	}
+	if opts.FeeDetails {
+		for _, line := range describeFeeTransaction(cfg.NumberFormat, option, feeTxn) {
+			fmt.Fprintln(out, "  "+line)
+		}
+	}
+
+	feeTxns := append(swapTxns, feeTxn)
+
+	updated := make(sequence.Transactions, 0, len(feeTxns)+len(txs))
+	feeIndex := len(feeTxns) - 1
+	if position == feePositionLast {
+		feeIndex += len(txs)
+		updated = append(updated, txs...)
+		updated = append(updated, feeTxns...)
+
+		// The options were quoted without the payment; make sure the relayer
+		// still accepts the bundle with the payment at the end.
+		if _, _, err := wallet.FeeOptions(ctx, updated); err != nil {
+			return nil, nil, nil, fmt.Errorf("relayer rejected bundle with fee payment last: %w", err)
+		}
+	} else {
+		updated = append(updated, feeTxns...)
+		updated = append(updated, txs...)
+	}
+	fmt.Fprintf(out, "Placed the fee payment %s in the bundle of %d transactions\n", position, len(updated))
+
+	if cfg.FeeTransferCheck && !isNativeFeeOption(option) {
+		if err := verifyFeeTransfer(ctx, out, cfg, provider, wallet.Address(), updated, feeIndex, option); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	return updated, feeQuote, option, nil
+}
+
+// validateSelfCall checks a transaction that targets the wallet's own
+// address. Self-calls run the wallet's onlySelf admin functions, so they must
+// be plain calls without value.
+func validateSelfCall(txn *sequence.Transaction) error {
+	if txn.DelegateCall {
+		return errors.New("self-call must not be a delegatecall")
+	}
+	if txn.Value != nil && txn.Value.Sign() != 0 {
+		return fmt.Errorf("self-call must not carry value, got %s wei", txn.Value)
+	}
+	if len(txn.Data) < 4 {
+		return errors.New("self-call has no function selector")
+	}
+	return nil
+}
+
+// dedupeTransactions returns txs without exact duplicates, keeping the first
+// occurrence of each, along with the number removed. Transactions are
+// duplicates when their target, value, and calldata all match; gas limits and
+// flags are ignored.
+func dedupeTransactions(txs sequence.Transactions) (sequence.Transactions, int) {
+	type txKey struct {
+		to    common.Address
+		value string
+		data  string
+	}
+
+	seen := make(map[txKey]struct{}, len(txs))
+	kept := make(sequence.Transactions, 0, len(txs))
+	for _, txn := range txs {
+		key := txKey{to: txn.To, value: "0", data: string(txn.Data)}
+		if txn.Value != nil {
+			key.value = txn.Value.String()
+		}
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		kept = append(kept, txn)
+	}
+	return kept, len(txs) - len(kept)
+}
+
+// errUnexpectedZeroFee is returned when ZeroFeeCheck is "abort" and a bundle
+// would be relayed for free.
+var errUnexpectedZeroFee = errors.New("unexpected zero relayer fee")
+
+// checkZeroFee applies cfg.ZeroFeeCheck to a bundle the relayer would carry
+// for free, for the given reason. Declared sponsorship skips the check.
+func checkZeroFee(out io.Writer, cfg *Config, reason string) error {
+	if cfg.SponsoredFees {
+		return nil
+	}
+	switch cfg.ZeroFeeCheck {
+	case "warn":
+		fmt.Fprintf(out, "Warning: %s; relaying for free. Set sponsoredFees if this is intended.\n", reason)
+	case "abort":
+		return fmt.Errorf("%w: %s (set sponsoredFees if sponsorship is intended)", errUnexpectedZeroFee, reason)
+	}
+	return nil
+}
+
+// encodeMintCalldata packs the arguments for mint(address,uint256,uint256,bytes).
+func encodeMintCalldata(to common.Address, tokenID, amount *big.Int, data []byte) ([]byte, error) {
+	if tokenID == nil || amount == nil {
+		return nil, errors.New("tokenID and amount cannot be nil")
+	}
+	if data == nil {
+		data = []byte{}
+	}
+	return mintFunction.Pack("mint", to, tokenID, amount, data)
+}
+
+// erc1155InterfaceID is the ERC-165 interface ID of ERC-1155.
+var erc1155InterfaceID = [4]byte{0xd9, 0xb6, 0x7a, 0x26}
+
+// CheckMintTarget checks, before any fee is spent, that call.To looks like
+// the contract the mints expect: it has code, and a trial eth_call of mint
+// from the wallet doesn't revert (which also catches a missing minter role).
+// Problems are printed as warnings, or returned as an error when strict is
+// set. Missing ERC-165 support for ERC-1155 is only reported, since mint
+// helpers needn't implement it.
+func CheckMintTarget(ctx context.Context, out io.Writer, cfg *Config, provider *ethrpc.Provider, walletAddr common.Address, call CallSpec, strict bool) error {
+	fmt.Fprintf(out, "Checking mint target %s...\n", call.To.Hex())
+
+	var problems []string
+
+	code, err := provider.CodeAt(ctx, call.To, nil)
+	if err != nil {
+		return fmt.Errorf("fetch code: %w", err)
+	}
+	if len(code) == 0 {
+		problems = append(problems, "no contract code at the target address")
+	} else {
+		if ok, err := supportsInterface(ctx, provider, call.To, erc1155InterfaceID); err != nil || !ok {
+			fmt.Fprintln(out, "Note: target does not report ERC-1155 support via ERC-165")
+		}
+
+		data, err := encodeMintCalldata(walletAddr, big.NewInt(1), big.NewInt(1), nil)
+		if err != nil {
+			return fmt.Errorf("encode calldata: %w", err)
+		}
+		msg := ethereum.CallMsg{From: walletAddr, To: &call.To, Data: data}
+		if call.Value != nil && call.Value.Sign() > 0 {
+			msg.Value = call.Value
+		}
+		if _, err := provider.CallContract(ctx, msg, nil); err != nil {
+			if data, ok := revertData(err); ok {
+				problems = append(problems, fmt.Sprintf("trial mint call from the wallet reverted: %s", cfg.describeRevert(data)))
+			} else {
+				problems = append(problems, fmt.Sprintf("trial mint call from the wallet failed: %v", err))
+			}
+		}
+	}
+
+	if len(problems) == 0 {
+		fmt.Fprintln(out, "Mint target looks as expected.")
+		return nil
+	}
+	if strict {
+		return fmt.Errorf("target %s: %s", call.To.Hex(), strings.Join(problems, "; "))
+	}
+	for _, problem := range problems {
+		fmt.Fprintf(out, "Warning: mint target %s: %s\n", call.To.Hex(), problem)
+	}
+	return nil
+}
+
+// errMissingInterface is returned when the target doesn't report supporting
+// an interface in RequireInterfaces.
+var errMissingInterface = errors.New("target does not support a required interface")
+
+// parseInterfaceID parses an ERC-165 interface ID given as 4-byte hex, e.g.
+// "0xd9b67a26".
+func parseInterfaceID(s string) ([4]byte, error) {
+	var id [4]byte
+	b, err := hexutil.Decode(s)
+	if err != nil || len(b) != len(id) {
+		return id, fmt.Errorf("invalid interface id %q (want 4 bytes of 0x-prefixed hex)", s)
+	}
+	copy(id[:], b)
+	return id, nil
+}
+
+// CheckRequiredInterfaces asks target, via ERC-165, about each interface in
+// cfg.RequireInterfaces and fails with errMissingInterface unless it reports
+// supporting all of them. A target without ERC-165 fails too.
+func CheckRequiredInterfaces(ctx context.Context, out io.Writer, cfg *Config, provider *ethrpc.Provider, target common.Address) error {
+	var missing []string
+	for _, s := range cfg.RequireInterfaces {
+		id, err := parseInterfaceID(s)
+		if err != nil {
+			return err
+		}
+		ok, err := supportsInterface(ctx, provider, target, id)
+		switch {
+		case err != nil:
+			missing = append(missing, fmt.Sprintf("%s (supportsInterface failed: %v)", s, err))
+		case !ok:
+			missing = append(missing, s)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s does not support %s", errMissingInterface, target.Hex(), strings.Join(missing, ", "))
+	}
+	fmt.Fprintf(out, "Target supports required interfaces: %s\n", strings.Join(cfg.RequireInterfaces, ", "))
+	return nil
+}
+
+// supportsInterface asks addr whether it implements interfaceID via ERC-165.
+func supportsInterface(ctx context.Context, provider *ethrpc.Provider, addr common.Address, interfaceID [4]byte) (bool, error) {
+	calldata, err := erc165ABI.Pack("supportsInterface", interfaceID)
+	if err != nil {
+		return false, fmt.Errorf("encode supportsInterface: %w", err)
+	}
+	output, err := provider.CallContract(ctx, ethereum.CallMsg{To: &addr, Data: calldata}, nil)
+	if err != nil {
+		return false, err
+	}
+	results, err := erc165ABI.Unpack("supportsInterface", output)
+	if err != nil {
+		return false, fmt.Errorf("decode supportsInterface: %w", err)
+	}
+	ok, _ := results[0].(bool)
+	return ok, nil
+}
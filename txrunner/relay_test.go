@@ -0,0 +1,44 @@
+package txrunner
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/0xsequence/ethkit/ethrpc"
+	"github.com/0xsequence/ethkit/go-ethereum/common"
+	sequence "github.com/0xsequence/go-sequence"
+)
+
+func TestCheckBundleGasReusesBlockGasLimitWithoutSimulating(t *testing.T) {
+	ctx := context.Background()
+	node := newFakeNode(t)
+	node.gasLimit = 150_000
+	provider, err := ethrpc.NewProvider(node.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runner := NewRunner(&Config{}, nil, provider, nil, nil)
+
+	target := common.HexToAddress("0x0000000000000000000000000000000000000e01")
+	bundle := func(pinned int64) sequence.Transactions {
+		return sequence.Transactions{
+			{To: target, GasLimit: big.NewInt(pinned)},
+			{To: target}, // auto gas limit, not simulated without SimulateBundleGas
+		}
+	}
+
+	if err := runner.checkBundleGas(ctx, bundle(100_000)); err != nil {
+		t.Fatalf("bundle within the block gas limit: %v", err)
+	}
+	if err := runner.checkBundleGas(ctx, bundle(200_000)); !errors.Is(err, errBundleExceedsBlockGas) {
+		t.Fatalf("bundle above the block gas limit: got %v, want %v", err, errBundleExceedsBlockGas)
+	}
+	if got := node.count("eth_getBlockByNumber"); got != 1 {
+		t.Errorf("read the latest block %d times, want 1", got)
+	}
+	if got := node.count("eth_call"); got != 0 {
+		t.Errorf("sent %d eth_call(s) without simulateBundleGas, want 0", got)
+	}
+}
@@ -0,0 +1,129 @@
+package txrunner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/0xsequence/ethkit/ethrpc"
+	"github.com/0xsequence/ethkit/ethrpc/jsonrpc"
+	"github.com/0xsequence/ethkit/go-ethereum"
+	"github.com/0xsequence/ethkit/go-ethereum/accounts/abi"
+	"github.com/0xsequence/ethkit/go-ethereum/common"
+	"github.com/0xsequence/ethkit/go-ethereum/common/hexutil"
+	"github.com/0xsequence/ethkit/go-ethereum/core/types"
+)
+
+// ---------------------------------------------------------------------------
+// Revert decoding
+// ---------------------------------------------------------------------------
+
+// describeRevert renders revert data as Error(string) and Panic(uint256)
+// reasons, or as a custom error registered from cfg.ErrorABIs or the wallet,
+// with its arguments. Byte arguments that are themselves revert data, such as
+// the inner error the wallet wraps in Reverted, are decoded in turn.
+func (c *Config) describeRevert(data []byte) string {
+	if len(data) == 0 {
+		return "reverted without data"
+	}
+	if len(data) < 4 {
+		return "revert data " + hexutil.Encode(data)
+	}
+	if reason, err := abi.UnpackRevert(data); err == nil {
+		if bytes.Equal(data[:4], panicSelector) {
+			return fmt.Sprintf("Panic(%s)", reason)
+		}
+		return fmt.Sprintf("Error(%q)", reason)
+	}
+
+	e, ok := c.revertErrors[[4]byte(data[:4])]
+	if !ok {
+		return fmt.Sprintf("unknown error %s (data %s)", hexutil.Encode(data[:4]), hexutil.Encode(data))
+	}
+	values, err := e.Inputs.Unpack(data[4:])
+	if err != nil {
+		return fmt.Sprintf("%s (undecodable arguments: %v)", e.Name, err)
+	}
+	args := make([]string, len(values))
+	for i, value := range values {
+		name := e.Inputs[i].Name
+		if name == "" {
+			name = fmt.Sprintf("arg%d", i)
+		}
+		args[i] = name + "=" + c.describeRevertArg(value)
+	}
+	return fmt.Sprintf("%s(%s)", e.Name, strings.Join(args, ", "))
+}
+
+// panicSelector is the selector of Panic(uint256).
+var panicSelector = []byte{0x4e, 0x48, 0x7b, 0x71}
+
+func (c *Config) describeRevertArg(value any) string {
+	switch v := value.(type) {
+	case []byte:
+		if len(v) >= 4 && (len(v)-4)%32 == 0 {
+			if _, ok := c.revertErrors[[4]byte(v[:4])]; ok {
+				return c.describeRevert(v)
+			}
+			if _, err := abi.UnpackRevert(v); err == nil {
+				return c.describeRevert(v)
+			}
+		}
+		return hexutil.Encode(v)
+	case common.Address:
+		return v.Hex()
+	case *big.Int, string, bool:
+		return fmt.Sprint(v)
+	default:
+		// Structs such as the wallet's decoded payload are too large to be
+		// useful in a one-line reason.
+		return "{…}"
+	}
+}
+
+// revertData extracts the revert data a node attached to a failed eth_call.
+func revertData(err error) ([]byte, bool) {
+	var rpcErr jsonrpc.Error
+	if !errors.As(err, &rpcErr) || len(rpcErr.Data) == 0 {
+		return nil, false
+	}
+	var hexData string
+	if json.Unmarshal(rpcErr.Data, &hexData) != nil {
+		return nil, false
+	}
+	data, err := hexutil.Decode(hexData)
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// replayRevert re-executes the transaction of a failed receipt with eth_call
+// against the state before its block, and describes the revert. Earlier
+// transactions in the same block aren't replayed, so the result may differ
+// from what happened on-chain.
+func replayRevert(ctx context.Context, cfg *Config, provider *ethrpc.Provider, receipt *types.Receipt) (string, error) {
+	tx, _, err := provider.TransactionByHash(ctx, receipt.TxHash)
+	if err != nil {
+		return "", fmt.Errorf("fetch transaction: %w", err)
+	}
+	from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+	if err != nil {
+		return "", fmt.Errorf("recover sender: %w", err)
+	}
+	msg := ethereum.CallMsg{From: from, To: tx.To(), Gas: tx.Gas(), Value: tx.Value(), Data: tx.Data()}
+	parent := new(big.Int).Sub(receipt.BlockNumber, big.NewInt(1))
+	_, err = provider.CallContract(ctx, msg, parent)
+	if err == nil {
+		return "", errors.New("replay did not revert")
+	}
+	data, ok := revertData(err)
+	if !ok {
+		return "", err
+	}
+	return cfg.describeRevert(data), nil
+}
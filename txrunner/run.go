@@ -0,0 +1,439 @@
+package txrunner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"time"
+
+	"github.com/0xsequence/ethkit/go-ethereum/common"
+	"github.com/0xsequence/go-sequence/relayer"
+)
+
+// ---------------------------------------------------------------------------
+// Run — one full run, as the command line drives it
+// ---------------------------------------------------------------------------
+
+// receiptFlushTimeout bounds how long a finished run waits for queued
+// receipt records to be written.
+const receiptFlushTimeout = 30 * time.Second
+
+// RunOptions selects what Run does. The zero value mints once, in sync
+// mode.
+type RunOptions struct {
+	// Count is the number of mints to send, 1 if zero. Async sends them in
+	// parallel instead of one after another.
+	Count int
+	Async bool
+	// DryRun quotes and signs each bundle and prints it without relaying;
+	// nothing is deployed, published or funded.
+	DryRun bool
+	// CallValue is the native value in wei attached to each mint call.
+	CallValue *big.Int
+	// Label is stored with each receipt record.
+	Label string
+
+	// Admin, Send and FleetOwners select a run other than minting: a
+	// wallet administration self-call, another send operation, or the
+	// fleet command Fleet over a list of owners.
+	Admin       *AdminOp
+	Send        *SendOp
+	Fleet       string
+	FleetOwners []common.Address
+
+	// FeeTokens lists the relayer's fee tokens, History prints the last
+	// History receipt records, and Context prints the wallet context as
+	// ContextFormat ("text" or "json"); each is the whole run.
+	FeeTokens     bool
+	History       int
+	ContextFormat string
+
+	// Explain describes the steps a run would take and stops, EmitDeploy
+	// prints the wallet's deploy data and stops, and PreviewSwap quotes the
+	// fee swap for one mint and stops.
+	Explain     bool
+	EmitDeploy  bool
+	PreviewSwap bool
+
+	// ShowQR prints the wallet address as a terminal QR code; QRPNGPath
+	// also writes it to a PNG file.
+	ShowQR    bool
+	QRPNGPath string
+
+	FeeDetails bool
+	Dedupe     bool
+	// CheckTarget checks the mint target looks mintable and warns if not;
+	// StrictTarget aborts instead.
+	CheckTarget  bool
+	StrictTarget bool
+	// StrictDirectory aborts when the wallet config can't be confirmed in
+	// the directory instead of warning.
+	StrictDirectory bool
+
+	// BundleTextPath, when set, is where each bundle is written in decoded
+	// text form; "-" writes it to the runner's Out.
+	BundleTextPath string
+
+	// Errors receives the failures that don't end the run, such as a failed
+	// revoke. When JSON is set, each failed mint is reported to it too. Its
+	// Wallet is filled in once the wallet is set up.
+	Errors *ErrorReporter
+}
+
+// RunError is an error that ended a run, with the step it ended in, e.g.
+// "deploy wallet".
+type RunError struct {
+	Op  string
+	Err error
+}
+
+func (e *RunError) Error() string { return e.Op + ": " + e.Err.Error() }
+func (e *RunError) Unwrap() error { return e.Err }
+
+// NewRunnerFromConfig sets up the configured signer's wallet and connects
+// a runner for it to cfg's node and relayer, printing to out. Nothing is
+// sent over the network until the runner is used.
+func NewRunnerFromConfig(cfg *Config, out io.Writer) (*Runner, error) {
+	eoa, err := NewEOA(cfg)
+	if err != nil {
+		return nil, &RunError{Op: "init signer", Err: err}
+	}
+	wallet, err := NewWallet(cfg, NewSigner(cfg, eoa))
+	if err != nil {
+		return nil, &RunError{Op: "init wallet", Err: err}
+	}
+	provider, err := NewProvider(out, cfg, WithAccessKey(cfg.NodeURL, cfg.ProjectAccessKey))
+	if err != nil {
+		return nil, &RunError{Op: "init provider", Err: err}
+	}
+	eoa.SetProvider(provider)
+	relayerClient, err := relayer.NewClient(cfg.RelayerURL, cfg.ProjectAccessKey, provider)
+	if err != nil {
+		return nil, &RunError{Op: "init relayer", Err: err}
+	}
+	runner := NewRunner(cfg, wallet, provider, relayerClient, eoa)
+	runner.Out = out
+	return runner, nil
+}
+
+// Run carries out one run as selected by opts: it publishes and deploys
+// the wallet as needed, then sends the mints or the selected operation and
+// prints their summary to the runner's Out. An error ending the run is a
+// *RunError; a mint failing post-verification ends it with one wrapping
+// ErrPostVerify, after the rest have been sent.
+func (r *Runner) Run(ctx context.Context, opts RunOptions) error {
+	cfg, wallet, out := r.Config, r.Wallet, r.out()
+	count := max(opts.Count, 1)
+	callValue := opts.CallValue
+	if callValue == nil {
+		callValue = new(big.Int)
+	}
+
+	if opts.DryRun && opts.FleetOwners != nil {
+		return &RunError{Op: "dry-run", Err: fmt.Errorf("%s sends from the EOA directly and has no dry run", opts.Fleet)}
+	}
+	if opts.Send != nil && (count != 1 || opts.Async) {
+		return &RunError{Op: "send", Err: fmt.Errorf("-count and -async apply to mint only, not %s", opts.Send.Name)}
+	}
+	if opts.Send != nil && (opts.Explain || opts.PreviewSwap) {
+		return &RunError{Op: "send", Err: fmt.Errorf("-explain and -preview-swap describe mint runs only, not %s", opts.Send.Name)}
+	}
+
+	if opts.History > 0 {
+		if err := PrintReceiptHistory(ctx, out, cfg.ReceiptStore, opts.History); err != nil {
+			return &RunError{Op: "history", Err: err}
+		}
+		return nil
+	}
+	if opts.ContextFormat != "" {
+		if err := PrintContextReport(out, cfg, opts.ContextFormat == "json"); err != nil {
+			return &RunError{Op: "context", Err: err}
+		}
+		return nil
+	}
+
+	fmt.Fprintln(out, "--- Sequence V3 Transaction Example ---")
+	if chain, ok := knownChains[cfg.ChainID]; ok {
+		fmt.Fprintf(out, "Chain ID: %d (%s)\n", cfg.ChainID, chain.Name)
+	} else {
+		fmt.Fprintf(out, "Chain ID: %d\n", cfg.ChainID)
+	}
+	if opts.FleetOwners != nil {
+		fmt.Fprintf(out, "Mode:     %s (%d wallets)\n", opts.Fleet, len(opts.FleetOwners))
+		PrintWalletContext(out, cfg)
+		if err := r.RunFleetCommand(ctx, opts.Fleet, opts.FleetOwners); err != nil {
+			return &RunError{Op: opts.Fleet, Err: err}
+		}
+		return nil
+	}
+	if opts.FeeTokens {
+		fmt.Fprintln(out, "Mode:     fee-tokens")
+		if err := CheckFeeTokens(ctx, out, cfg, r.Relayer, true); err != nil {
+			return &RunError{Op: "fee-tokens", Err: err}
+		}
+		return nil
+	}
+	if opts.Admin != nil {
+		fmt.Fprintf(out, "Mode:     admin (%s)\n", opts.Admin.Name)
+	} else if opts.Send != nil {
+		fmt.Fprintf(out, "Mode:     send (%s)\n", opts.Send.Name)
+	} else if opts.Async {
+		fmt.Fprintf(out, "Mode:     async (%d transactions)\n", count)
+	} else {
+		fmt.Fprintf(out, "Mode:     sync (%d transactions)\n", count)
+	}
+	if opts.DryRun {
+		fmt.Fprintln(out, "Dry run:  bundles are quoted and signed, never relayed")
+	}
+
+	// -----------------------------------------------------------------------
+	// Wallet setup — the Sequence smart wallet of the configured EOA signer.
+	// -----------------------------------------------------------------------
+
+	if r.Deployer == nil {
+		return &RunError{Op: "init signer", Err: errors.New("runner has no signer EOA")}
+	}
+	if opts.Errors != nil {
+		opts.Errors.Wallet = wallet.Address()
+	}
+	if err := CheckWalletAddress(cfg, wallet.Address()); err != nil {
+		return &RunError{Op: "init wallet", Err: err}
+	}
+
+	fmt.Fprintf(out, "Signer Address (EOA): %s\n", r.Deployer.Address().Hex())
+	fmt.Fprintf(out, "Smart Wallet Address: %s\n", wallet.Address().Hex())
+	fmt.Fprintf(out, "Image Hash:           %s\n", wallet.GetWalletConfig().ImageHash().Hex())
+	if cfg.SignatureScheme != "" {
+		fmt.Fprintf(out, "Signature Scheme:     %s\n", cfg.SignatureScheme)
+	}
+	if cfg.WalletCheckpoint != 0 {
+		fmt.Fprintf(out, "Wallet Checkpoint:    %d\n", cfg.WalletCheckpoint)
+	}
+	PrintWalletContext(out, cfg)
+	fmt.Fprintf(out, "Target Address:       %s\n", cfg.TargetAddress)
+	if callValue.Sign() > 0 {
+		fmt.Fprintf(out, "Call Value:           %s per mint\n", cfg.NumberFormat.Native(callValue))
+	}
+
+	if opts.ShowQR || opts.QRPNGPath != "" {
+		if err := PrintAddressQR(out, wallet.Address(), cfg.ChainID, opts.ShowQR, opts.QRPNGPath); err != nil {
+			return &RunError{Op: "qr", Err: err}
+		}
+	}
+
+	if opts.EmitDeploy {
+		if err := PrintDeployData(out, wallet); err != nil {
+			return &RunError{Op: "emit deploy", Err: err}
+		}
+		return nil
+	}
+
+	if opts.Explain {
+		PrintExplanation(out, cfg, wallet, r.Deployer.Address(), opts.Admin, opts.Async, count, callValue)
+		return nil
+	}
+
+	// -----------------------------------------------------------------------
+	// Provider & relayer — connect the wallet to the network and relay service.
+	// -----------------------------------------------------------------------
+
+	if err := r.Connect(ctx); err != nil {
+		return &RunError{Op: "connect wallet", Err: err}
+	}
+
+	// A misconfigured allowlist would otherwise only show up as no
+	// affordable fee option once a bundle is quoted.
+	if len(cfg.FeeTokenAllowlist) > 0 {
+		if err := CheckFeeTokens(ctx, out, cfg, r.Relayer, false); err != nil {
+			fmt.Fprintf(out, "Note: could not check feeTokenAllowlist against the relayer (%v)\n", err)
+		}
+	}
+
+	fallbackRelayers, err := ConnectFallbackRelayers(cfg, NewSigner(cfg, r.Deployer), r.Provider)
+	if err != nil {
+		return &RunError{Op: "connect fallback relayers", Err: err}
+	}
+
+	call := CallSpec{To: common.HexToAddress(cfg.TargetAddress), Value: callValue}
+	if opts.PreviewSwap {
+		if err := PreviewFeeSwap(ctx, out, cfg, wallet, r.Provider, call); err != nil {
+			return &RunError{Op: "preview swap", Err: err}
+		}
+		return nil
+	}
+
+	// -----------------------------------------------------------------------
+	// Publish wallet config to Keymachine (idempotent).
+	// -----------------------------------------------------------------------
+
+	sessions := NewSessionsClient(cfg)
+	if opts.DryRun {
+		fmt.Fprintln(out, "Dry run: not publishing the wallet configuration.")
+	} else if err := PublishWalletConfig(ctx, wallet, sessions); err != nil {
+		fmt.Fprintf(out, "Could not publish config (%v). Checking directory state...\n", err)
+		if err := ReconcileDirectory(ctx, out, wallet, sessions, err); err != nil {
+			if opts.StrictDirectory {
+				return &RunError{Op: "publish config", Err: err}
+			}
+			fmt.Fprintf(out, "Warning: %v. Continuing...\n", err)
+		}
+	} else {
+		fmt.Fprintln(out, "Wallet configuration published to directory.")
+	}
+
+	// -----------------------------------------------------------------------
+	// Deploy the wallet on-chain if it is still counterfactual.
+	// -----------------------------------------------------------------------
+
+	fmt.Fprintln(out, "Checking wallet deployment status...")
+	if opts.DryRun {
+		deployed, err := wallet.IsDeployed()
+		if err != nil {
+			return &RunError{Op: "deploy wallet", Err: fmt.Errorf("check deployment: %w", err)}
+		}
+		if deployed {
+			fmt.Fprintln(out, "Wallet already deployed on-chain.")
+		} else {
+			fmt.Fprintln(out, "Dry run: wallet is not deployed; a real run would deploy it first.")
+		}
+	} else {
+		if err := r.EnsureWalletDeployed(ctx); err != nil {
+			return &RunError{Op: "deploy wallet", Err: err}
+		}
+		if cfg.WalletMinNativeBalance != "" {
+			if err := EnsureWalletNativeBalance(ctx, out, cfg, r.Provider, r.Deployer, wallet.Address()); err != nil {
+				return &RunError{Op: "wallet native balance", Err: err}
+			}
+		}
+	}
+
+	// -----------------------------------------------------------------------
+	// Send transactions — the admin op, the send op, or the mints.
+	// -----------------------------------------------------------------------
+
+	sendOpts := SendOptions{DryRun: opts.DryRun, Dedupe: opts.Dedupe, FeeDetails: opts.FeeDetails, FallbackRelayers: fallbackRelayers}
+	if opts.BundleTextPath != "" {
+		sendOpts.BundleText = &BundleTextLog{}
+	}
+	writeBundleText := func() {
+		if sendOpts.BundleText == nil {
+			return
+		}
+		if opts.BundleTextPath == "-" {
+			fmt.Fprint(out, sendOpts.BundleText.String())
+			return
+		}
+		if err := sendOpts.BundleText.WriteFile(opts.BundleTextPath); err != nil {
+			fmt.Fprintf(out, "Warning: could not write bundle text: %v\n", err)
+		}
+	}
+
+	if opts.Admin == nil && opts.Send == nil && (opts.CheckTarget || opts.StrictTarget) {
+		if err := CheckMintTarget(ctx, out, cfg, r.Provider, wallet.Address(), call, opts.StrictTarget); err != nil {
+			return &RunError{Op: "check target", Err: err}
+		}
+	}
+	if opts.Admin == nil && len(cfg.RequireInterfaces) > 0 {
+		if err := CheckRequiredInterfaces(ctx, out, cfg, r.Provider, call.To); err != nil {
+			return &RunError{Op: "check target", Err: err}
+		}
+	}
+
+	if opts.Admin != nil {
+		sendOpts.BundleLabel = "admin " + opts.Admin.Name
+		err := r.RunAdminOp(ctx, sendOpts, opts.Admin)
+		writeBundleText()
+		if err != nil {
+			return &RunError{Op: "admin " + opts.Admin.Name, Err: err}
+		}
+		return nil
+	}
+
+	// Records are written in the background while the run goes on, and
+	// flushed before it returns. A dry run relays nothing, so it doesn't
+	// open the store.
+	var receipts *ReceiptWriter
+	if !opts.DryRun {
+		store, err := NewReceiptStore(ctx, cfg.ReceiptStore)
+		if err != nil {
+			return &RunError{Op: "init receipt store", Err: err}
+		}
+		if store != nil {
+			receipts = NewReceiptWriter(store, cfg.ReceiptStore.BufferSize)
+		}
+	}
+	storeReceipts := func(results []TxResult) {
+		if receipts == nil {
+			return
+		}
+		if err := StoreReceipts(ctx, receipts, results, cfg.ChainID, wallet.Address(), wallet.GetWalletContext(), opts.Label); err != nil {
+			fmt.Fprintf(out, "Warning: %v\n", err)
+		}
+	}
+	flushReceipts := func() {
+		if receipts == nil {
+			return
+		}
+		flushCtx, cancel := context.WithTimeout(ctx, receiptFlushTimeout)
+		defer cancel()
+		if err := receipts.Close(flushCtx); err != nil {
+			fmt.Fprintf(out, "Warning: receipt store: %v\n", err)
+		}
+	}
+
+	if opts.Send != nil {
+		sendOpts.BundleLabel = opts.Send.Description
+		result := r.RunSendOp(ctx, sendOpts, call, opts.Send)
+		storeReceipts([]TxResult{result})
+		PrintResultsSummary(out, []TxResult{result}, cfg)
+		writeBundleText()
+		flushReceipts()
+		if result.Err != nil {
+			return &RunError{Op: "send " + opts.Send.Name, Err: WithMetaTxnID(result.MetaTxnID, result.Err)}
+		}
+		return nil
+	}
+
+	var results []TxResult
+	if opts.Async {
+		results = r.SendAsync(ctx, call, sendOpts, count)
+	} else {
+		results = r.SendSync(ctx, call, sendOpts, count)
+	}
+	storeReceipts(results)
+	PrintResultsSummary(out, results, cfg)
+
+	// A failed revoke leaves the allowance as it was; the mints stand.
+	if cfg.RevokeAllowanceAfter && !opts.DryRun {
+		sendOpts.BundleLabel = "revoke allowance"
+		sendOpts.BundleOrder = len(results)
+		if err := r.RevokeAllowances(ctx, sendOpts, results); err != nil {
+			opts.Errors.Report("revoke allowance", err)
+		}
+	}
+	writeBundleText()
+	flushReceipts()
+
+	// Text output already listed failed mints in the summary.
+	if opts.Errors != nil && opts.Errors.JSON {
+		for _, res := range results {
+			if res.Err != nil {
+				opts.Errors.Report("mint", mintError(res))
+			}
+		}
+	}
+	for _, res := range results {
+		if errors.Is(res.Err, ErrPostVerify) {
+			return &RunError{Op: "mint", Err: mintError(res)}
+		}
+	}
+	return nil
+}
+
+// mintError is res.Err annotated with the mint's token ID and
+// meta-transaction ID.
+func mintError(res TxResult) error {
+	return WithMetaTxnID(res.MetaTxnID, fmt.Errorf("tokenId=%d: %w", res.TokenID, res.Err))
+}
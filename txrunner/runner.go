@@ -0,0 +1,565 @@
+package txrunner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/0xsequence/ethkit/ethcoder"
+	"github.com/0xsequence/ethkit/ethrpc"
+	"github.com/0xsequence/ethkit/ethwallet"
+	"github.com/0xsequence/ethkit/go-ethereum"
+	"github.com/0xsequence/ethkit/go-ethereum/common"
+	"github.com/0xsequence/ethkit/go-ethereum/core/types"
+	sequence "github.com/0xsequence/go-sequence"
+	v3 "github.com/0xsequence/go-sequence/core/v3"
+	"github.com/0xsequence/go-sequence/relayer"
+)
+
+// ---------------------------------------------------------------------------
+// Mint call, send options and transaction results
+// ---------------------------------------------------------------------------
+
+// CallSpec describes the contract call each mint transaction makes: the
+// target contract and the native value (in wei) attached to the call.
+type CallSpec struct {
+	To    common.Address
+	Value *big.Int
+}
+
+// SendOptions holds per-run switches that change how bundles are prepared
+// before signing.
+type SendOptions struct {
+	// DryRun signs each bundle and prints it instead of relaying it.
+	DryRun bool
+	// Dedupe drops exact-duplicate transactions (same to, value, and data)
+	// from a bundle.
+	Dedupe bool
+	// FeeDetails prints the decoded fee payment transaction.
+	FeeDetails bool
+
+	// BundleText, when set, records each bundle in decoded text form before
+	// it is signed. BundleLabel and BundleOrder identify and order the
+	// bundle being sent.
+	BundleText  *BundleTextLog
+	BundleLabel string
+	BundleOrder int
+
+	// FallbackRelayers are asked for fee options, in order, when the primary
+	// relayer's can't be fetched or none is affordable.
+	FallbackRelayers []FallbackRelayer
+
+	// FeeQuote, when set, holds fee options already fetched for the bundle
+	// being sent, used instead of asking the relayer again.
+	FeeQuote *FeeQuote
+}
+
+// FeeQuote is the relayer's answer to a fee options request for one bundle.
+type FeeQuote struct {
+	Options []*sequence.RelayerFeeOption
+	Quote   *sequence.RelayerFeeQuote
+	Err     error
+}
+
+// TxResult holds the outcome of a single relayed transaction. Used in both
+// sync and async paths to collect results uniformly.
+type TxResult struct {
+	Index     int
+	TokenID   int64
+	MetaTxnID sequence.MetaTxnID
+	TxHash    string
+	Receipt   *types.Receipt
+	Fee       *sequence.RelayerFeeOption
+	// Approvals are the allowances the relayed bundle grants.
+	Approvals []Approval
+	// Confirmation is the wall-clock time from the relayer accepting the
+	// bundle to its receipt being confirmed, or zero if it never confirmed.
+	Confirmation time.Duration
+	// Calls is the outcome of each call in the bundle, decoded from the
+	// wallet's events in Receipt, in bundle order.
+	Calls []CallOutcome
+	// DryRun marks a bundle that was signed but not relayed.
+	DryRun bool
+	Err    error
+}
+
+// CallOutcome is how one call of a relayed bundle ended, as reported by the
+// wallet's CallSucceeded, CallFailed, CallAborted and CallSkipped events.
+type CallOutcome struct {
+	Index  int    `json:"index"`
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// ---------------------------------------------------------------------------
+// Runner
+// ---------------------------------------------------------------------------
+
+// Runner sends bundles from one smart wallet. It holds what every send
+// needs: the config, the wallet and the provider and relayer it is connected
+// to, the EOA that deploys the wallet, and the balance cache fee selection
+// reads through.
+type Runner struct {
+	Config   *Config
+	Wallet   *sequence.Wallet[*v3.WalletConfig]
+	Provider *ethrpc.Provider
+	Relayer  *relayer.Client
+	// Deployer deploys the wallet when it is not on-chain yet, including
+	// when a relay rejected for an undeployed wallet is retried and the
+	// deployment doesn't show up on its own. It may be nil for a runner
+	// that never deploys.
+	Deployer *ethwallet.Wallet
+	// Out receives the progress and reports the runner prints; when nil,
+	// nothing is printed.
+	Out io.Writer
+
+	balances      *balanceCache
+	blockGasLimit *blockGasLimitCache
+}
+
+// out returns Out, or io.Discard when it is unset.
+func (r *Runner) out() io.Writer {
+	if r.Out == nil {
+		return io.Discard
+	}
+	return r.Out
+}
+
+// NewRunner returns a runner for wallet with an empty balance cache. Call
+// Connect before sending.
+func NewRunner(cfg *Config, wallet *sequence.Wallet[*v3.WalletConfig], provider *ethrpc.Provider, relayerClient *relayer.Client, deployer *ethwallet.Wallet) *Runner {
+	return &Runner{
+		Config:   cfg,
+		Wallet:   wallet,
+		Provider: provider,
+		Relayer:  relayerClient,
+		Deployer: deployer,
+		balances: newBalanceCache(cfg.balanceCacheTTL()),
+
+		blockGasLimit: new(blockGasLimitCache),
+	}
+}
+
+// Connect connects the runner's wallet to its provider and relayer; see
+// connectWallet.
+func (r *Runner) Connect(ctx context.Context) error {
+	return connectWallet(ctx, r.out(), r.Config, r.Wallet, r.Provider, r.Relayer)
+}
+
+// withWallet returns a copy of r that sends from wallet, sharing r's
+// provider, deployer, balance cache and block gas limit.
+func (r *Runner) withWallet(wallet *sequence.Wallet[*v3.WalletConfig]) *Runner {
+	c := *r
+	c.Wallet = wallet
+	return &c
+}
+
+// ---------------------------------------------------------------------------
+// Wallet connection
+// ---------------------------------------------------------------------------
+
+// connectWallet connects the wallet to the provider and relayer, then checks
+// both respond to a trivial read. Failures are retried with exponential
+// backoff so the tool tolerates dependencies that are still starting up.
+func connectWallet(ctx context.Context, out io.Writer, cfg *Config, wallet *sequence.Wallet[*v3.WalletConfig], provider *ethrpc.Provider, relayerClient *relayer.Client) error {
+	attempts := cfg.Retry.connectAttempts()
+	backoff := cfg.Retry.connectBackoff()
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		fmt.Fprintf(out, "Connecting wallet to provider and relayer (attempt %d/%d)...\n", attempt, attempts)
+
+		err = connectWalletOnce(ctx, wallet, provider, relayerClient)
+		if err == nil {
+			return nil
+		}
+		if attempt == attempts {
+			break
+		}
+
+		fmt.Fprintf(out, "Connect attempt %d failed: %v. Retrying in %s...\n", attempt, err, backoff)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+
+	return fmt.Errorf("after %d attempts: %w", attempts, err)
+}
+
+// FallbackRelayer is a copy of the wallet connected to a fallback relayer,
+// so fee options and the relay of a bundle always go to the same relayer.
+type FallbackRelayer struct {
+	URL    string
+	Wallet *sequence.Wallet[*v3.WalletConfig]
+}
+
+// ConnectFallbackRelayers connects a copy of signer's wallet to each of
+// cfg.FallbackRelayerURLs. The relayers aren't contacted until needed.
+func ConnectFallbackRelayers(cfg *Config, signer sequence.Signer, provider *ethrpc.Provider) ([]FallbackRelayer, error) {
+	fallbacks := make([]FallbackRelayer, 0, len(cfg.FallbackRelayerURLs))
+	for _, url := range cfg.FallbackRelayerURLs {
+		client, err := relayer.NewClient(url, cfg.ProjectAccessKey, provider)
+		if err != nil {
+			return nil, fmt.Errorf("relayer %s: %w", url, err)
+		}
+		wallet, err := NewWallet(cfg, signer)
+		if err != nil {
+			return nil, err
+		}
+		if err := wallet.Connect(provider, client); err != nil {
+			return nil, fmt.Errorf("relayer %s: %w", url, err)
+		}
+		fallbacks = append(fallbacks, FallbackRelayer{URL: url, Wallet: wallet})
+	}
+	return fallbacks, nil
+}
+
+func connectWalletOnce(ctx context.Context, wallet *sequence.Wallet[*v3.WalletConfig], provider *ethrpc.Provider, relayerClient *relayer.Client) error {
+	if err := wallet.Connect(provider, relayerClient); err != nil {
+		return err
+	}
+	if _, err := provider.BlockNumber(ctx); err != nil {
+		return fmt.Errorf("provider check: %w", err)
+	}
+	if _, err := relayerClient.Ping(ctx); err != nil {
+		return fmt.Errorf("relayer check: %w", err)
+	}
+	return nil
+}
+
+// ---------------------------------------------------------------------------
+// Sync path — send transactions one at a time, blocking between each.
+// ---------------------------------------------------------------------------
+
+// SendSync mints token IDs 1 through count, one bundle at a time, printing
+// each bundle's progress as it happens.
+func (r *Runner) SendSync(ctx context.Context, call CallSpec, opts SendOptions, count int) []TxResult {
+	out := r.out()
+	cfg, wallet := r.Config, r.Wallet
+	results := make([]TxResult, 0, count)
+
+	var quotes []*FeeQuote
+	if cfg.FeeQuoteConcurrency > 0 && count > 1 {
+		quotes = prefetchFeeQuotes(ctx, out, cfg, wallet, call, count)
+	}
+
+	for i := range count {
+		tokenID := int64(i + 1)
+		fmt.Fprintf(out, "\n[tx %d/%d] Sending mint for tokenId=%d...\n", i+1, count, tokenID)
+		if quotes != nil {
+			opts.FeeQuote = quotes[i]
+		}
+
+		// Print progress as it happens; drain it before printing the outcome.
+		progress := make(chan ProgressEvent, 8)
+		printed := make(chan struct{})
+		go func() {
+			defer close(printed)
+			for ev := range progress {
+				fmt.Fprintf(out, "[tx %d/%d] %s\n", i+1, count, ev.format(cfg.NumberFormat))
+			}
+		}()
+
+		result := r.sendOneMint(ctx, call, opts, i, tokenID, progress)
+		close(progress)
+		<-printed
+		results = append(results, result)
+
+		if result.Err != nil {
+			fmt.Fprintf(out, "[tx %d/%d] Failed: %v\n", i+1, count, result.Err)
+		} else {
+			fmt.Fprintf(out, "[tx %d/%d] Confirmed: %s\n", i+1, count, result.TxHash)
+		}
+	}
+
+	return results
+}
+
+// prefetchFeeQuotes fetches the fee options for each of count mint bundles,
+// at most cfg.FeeQuoteConcurrency at a time. Only the quotes are shared:
+// each bundle still selects its option against balances when it is sent. A
+// failed fetch leaves an Err, and that bundle asks the relayer again.
+func prefetchFeeQuotes(ctx context.Context, out io.Writer, cfg *Config, wallet *sequence.Wallet[*v3.WalletConfig], call CallSpec, count int) []*FeeQuote {
+	fmt.Fprintf(out, "\nFetching fee options for %d bundles (concurrency %d)...\n", count, cfg.FeeQuoteConcurrency)
+	start := time.Now()
+
+	quotes := make([]*FeeQuote, count)
+	sem := make(chan struct{}, cfg.FeeQuoteConcurrency)
+	var wg sync.WaitGroup
+
+	for i := range count {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			txs, err := buildMintTransaction(wallet, call, int64(idx+1))
+			if err != nil {
+				quotes[idx] = &FeeQuote{Err: err}
+				return
+			}
+			options, quote, err := wallet.FeeOptions(ctx, txs)
+			quotes[idx] = &FeeQuote{Options: options, Quote: quote, Err: err}
+		}(i)
+	}
+
+	wg.Wait()
+
+	failed := 0
+	for _, q := range quotes {
+		if q.Err != nil {
+			failed++
+		}
+	}
+	fmt.Fprintf(out, "Fetched fee options in %s (%d failed, refetched on send)\n", time.Since(start).Round(time.Millisecond), failed)
+	return quotes
+}
+
+// ---------------------------------------------------------------------------
+// Async path — fire all transactions concurrently and collect results.
+// ---------------------------------------------------------------------------
+
+// SendAsync mints token IDs 1 through count with one goroutine per bundle
+// and returns once all of them have finished.
+func (r *Runner) SendAsync(ctx context.Context, call CallSpec, opts SendOptions, count int) []TxResult {
+	fmt.Fprintf(r.out(), "\nFiring %d transactions in parallel...\n", count)
+
+	results := make([]TxResult, count)
+	var wg sync.WaitGroup
+
+	for i := range count {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			tokenID := int64(idx + 1)
+			results[idx] = r.sendOneMint(ctx, call, opts, idx, tokenID, nil)
+		}(i)
+	}
+
+	wg.Wait()
+	return results
+}
+
+// ---------------------------------------------------------------------------
+// Single mint transaction — shared by both sync and async paths.
+// ---------------------------------------------------------------------------
+
+// sendOneMint builds, relays, and waits for a single mint transaction.
+// It returns a TxResult capturing the outcome (success or error). Progress
+// events are sent to progress when it is non-nil.
+func (r *Runner) sendOneMint(ctx context.Context, call CallSpec, opts SendOptions, index int, tokenID int64, progress chan<- ProgressEvent) TxResult {
+	out := r.out()
+	cfg, wallet, provider := r.Config, r.Wallet, r.Provider
+	txs, err := buildMintTransaction(wallet, call, tokenID)
+	if err != nil {
+		return TxResult{Index: index, TokenID: tokenID, Err: err}
+	}
+
+	opts.BundleLabel = fmt.Sprintf("mint tokenId=%d", tokenID)
+	opts.BundleOrder = index
+
+	// The confirming event is searched for from the block the mint is sent in.
+	var fromBlock uint64
+	watchLog := cfg.ConfirmLog != nil
+	if watchLog {
+		fromBlock, err = provider.BlockNumber(ctx)
+		if err != nil {
+			fmt.Fprintf(out, "Could not read the block number (%v); falling back to receipt polling for tokenId=%d\n", err, tokenID)
+			watchLog = false
+		}
+	}
+
+	// Sign, attach fee payment, and relay via the Sequence relayer.
+	bundle, err := r.SendTransactionsWithFees(ctx, opts, txs, progress)
+	if err != nil {
+		return TxResult{Index: index, TokenID: tokenID, Err: fmt.Errorf("relay: %w", err)}
+	}
+	if bundle.Signed != nil {
+		return TxResult{Index: index, TokenID: tokenID, MetaTxnID: bundle.MetaTxnID, Fee: bundle.Fee, DryRun: true}
+	}
+
+	// Block until the chain confirms the transaction.
+	var receipt *types.Receipt
+	if watchLog {
+		receipt, err = waitForConfirmLog(ctx, out, cfg, provider, wallet.Address(), call.To, tokenID, fromBlock)
+		if errors.Is(err, errLogsUnavailable) {
+			fmt.Fprintf(out, "%v; falling back to receipt polling for tokenId=%d\n", err, tokenID)
+			receipt, err = waitForRelayedReceipt(ctx, out, cfg, provider, bundle)
+		}
+	} else {
+		receipt, err = waitForRelayedReceipt(ctx, out, cfg, provider, bundle)
+	}
+	if err != nil {
+		return TxResult{Index: index, TokenID: tokenID, MetaTxnID: bundle.MetaTxnID, Fee: bundle.Fee, Approvals: bundle.Approvals, Err: fmt.Errorf("wait: %w", err)}
+	}
+	emitProgress(progress, ProgressEvent{Kind: ProgressMined, MetaTxnID: bundle.MetaTxnID, Receipt: receipt})
+	var reverted error
+	if receipt.Status == types.ReceiptStatusSuccessful {
+		emitProgress(progress, ProgressEvent{Kind: ProgressConfirmed, MetaTxnID: bundle.MetaTxnID, Receipt: receipt})
+	} else {
+		reverted = errors.New("mint reverted")
+		if reason, err := replayRevert(ctx, cfg, provider, receipt); err == nil {
+			reverted = fmt.Errorf("mint reverted: %s", reason)
+		}
+	}
+
+	result := TxResult{
+		Index:        index,
+		TokenID:      tokenID,
+		MetaTxnID:    bundle.MetaTxnID,
+		TxHash:       receipt.TxHash.Hex(),
+		Receipt:      receipt,
+		Fee:          bundle.Fee,
+		Approvals:    bundle.Approvals,
+		Confirmation: time.Since(bundle.SubmittedAt),
+		Calls:        decodeCallOutcomes(receipt, wallet.Address(), bundle.MetaTxnID),
+		Err:          reverted,
+	}
+	if cfg.PostVerify != nil && receipt.Status == types.ReceiptStatusSuccessful {
+		if err := runPostVerify(ctx, out, cfg, provider, wallet.Address(), call.To, tokenID); err != nil {
+			result.Err = err
+		}
+	}
+	return result
+}
+
+// buildMintTransaction builds the mint of tokenID to the wallet described by
+// call.
+func buildMintTransaction(wallet *sequence.Wallet[*v3.WalletConfig], call CallSpec, tokenID int64) (sequence.Transactions, error) {
+	// Encode the mint(address,uint256,uint256,bytes) calldata.
+	mintCalldata, err := encodeMintCalldata(wallet.Address(), big.NewInt(tokenID), big.NewInt(1), nil)
+	if err != nil {
+		return nil, fmt.Errorf("encode calldata: %w", err)
+	}
+
+	value := big.NewInt(0)
+	if call.Value != nil {
+		value = cloneBigInt(call.Value)
+	}
+
+	return sequence.Transactions{{
+		To:            call.To,
+		Value:         value,
+		GasLimit:      autoGasLimit(),
+		Data:          mintCalldata,
+		DelegateCall:  false,
+		RevertOnError: true,
+	}}, nil
+}
+
+// decodeCallOutcomes returns the outcome of each call of the bundle opHash
+// executed by wallet, from the wallet's events in receipt. Calls that
+// emitted no event are omitted, so a reverted bundle yields none.
+func decodeCallOutcomes(receipt *types.Receipt, wallet common.Address, opHash sequence.MetaTxnID) []CallOutcome {
+	digest := common.HexToHash(string(opHash))
+
+	var outcomes []CallOutcome
+	for _, l := range receipt.Logs {
+		if l.Address != wallet {
+			continue
+		}
+
+		var (
+			hash   common.Hash
+			index  *big.Int
+			reason error
+			status string
+			err    error
+		)
+		switch {
+		case sequence.V3IsCallSucceededEvent(l, digest):
+			hash, index, err = sequence.V3DecodeCallSucceededEvent(l)
+			status = "succeeded"
+		case sequence.V3IsCallFailedEvent(l, digest):
+			hash, index, reason, err = sequence.V3DecodeCallFailedEvent(l)
+			status = "failed"
+		case sequence.V3IsCallAbortedEvent(l, digest):
+			hash, index, reason, err = sequence.V3DecodeCallAbortedEvent(l)
+			status = "aborted"
+		case sequence.V3IsCallSkippedEvent(l, digest):
+			hash, index, err = sequence.V3DecodeCallSkippedEvent(l)
+			status = "skipped"
+		default:
+			continue
+		}
+		if err != nil || hash != digest || !index.IsInt64() {
+			continue
+		}
+
+		outcome := CallOutcome{Index: int(index.Int64()), Status: status}
+		if reason != nil {
+			outcome.Reason = reason.Error()
+		}
+		outcomes = append(outcomes, outcome)
+	}
+
+	slices.SortFunc(outcomes, func(a, b CallOutcome) int { return a.Index - b.Index })
+	return outcomes
+}
+
+// partialCalls reports how many of calls succeeded, and whether any did not.
+func partialCalls(calls []CallOutcome) (succeeded int, partial bool) {
+	for _, c := range calls {
+		if c.Status == "succeeded" {
+			succeeded++
+		}
+	}
+	return succeeded, succeeded < len(calls)
+}
+
+// ErrPostVerify marks a mint that confirmed but whose post-verification
+// failed.
+var ErrPostVerify = errors.New("post-verify failed")
+
+// runPostVerify performs cfg.PostVerify for the mint of tokenID and compares
+// the decoded result with the expected values.
+func runPostVerify(ctx context.Context, out io.Writer, cfg *Config, provider *ethrpc.Provider, walletAddr, target common.Address, tokenID int64) error {
+	pv := cfg.PostVerify
+
+	to := target
+	if pv.To != "" {
+		to = common.HexToAddress(pv.To)
+	}
+
+	replacer := strings.NewReplacer("{wallet}", walletAddr.Hex(), "{tokenId}", fmt.Sprint(tokenID), "{target}", target.Hex())
+	args := make([]string, len(pv.Args))
+	for i, arg := range pv.Args {
+		args[i] = replacer.Replace(arg)
+	}
+
+	calldata, err := ethcoder.ABIEncodeMethodCalldataFromStringValues(pv.Method, args)
+	if err != nil {
+		return fmt.Errorf("%w: encode %s: %v", ErrPostVerify, pv.Method, err)
+	}
+
+	output, err := provider.CallContract(ctx, ethereum.CallMsg{To: &to, Data: calldata}, nil)
+	if err != nil {
+		return fmt.Errorf("%w: call %s: %v", ErrPostVerify, pv.Method, err)
+	}
+
+	got, err := ethcoder.ABIUnpackAndStringify(pv.Returns, output)
+	if err != nil {
+		return fmt.Errorf("%w: decode %s result: %v", ErrPostVerify, pv.Method, err)
+	}
+
+	if len(got) != len(pv.Expect) {
+		return fmt.Errorf("%w: %s returned %d values, expected %d", ErrPostVerify, pv.Method, len(got), len(pv.Expect))
+	}
+	for i := range got {
+		if !strings.EqualFold(got[i], pv.Expect[i]) {
+			return fmt.Errorf("%w: %s returned %s at position %d, expected %s", ErrPostVerify, pv.Method, got[i], i, pv.Expect[i])
+		}
+	}
+	fmt.Fprintf(out, "Post-verify passed for tokenId=%d: %s returned %s\n", tokenID, pv.Method, strings.Join(got, ", "))
+	return nil
+}
@@ -0,0 +1,249 @@
+package txrunner
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/0xsequence/ethkit/go-ethereum/common"
+	"github.com/0xsequence/ethkit/go-ethereum/core/types"
+	sequence "github.com/0xsequence/go-sequence"
+	v3 "github.com/0xsequence/go-sequence/core/v3"
+)
+
+// ---------------------------------------------------------------------------
+// Send operations — the transactions a "send -op" run relays
+// ---------------------------------------------------------------------------
+
+const SendUsage = `  -op mint                         mint -count tokens to the wallet (the default run)
+  -op transfer -to <address> -token-id <id> [-amount <n>]
+                                   transfer ERC-1155 tokens the wallet holds on targetAddress
+  -op mint-batch -token-ids <id,...> [-amounts <n,...>] [-to <address>]
+                                   mint several tokens in one mintBatch call, to the wallet
+                                   unless -to is given; amounts default to 1 each
+`
+
+// sendOpNames lists the operations send -op accepts.
+var sendOpNames = []string{"mint", "transfer", "mint-batch"}
+
+// SendOp is an operation selected with send -op. Mint runs the regular mint
+// path, so only other operations set Build.
+type SendOp struct {
+	Name        string
+	Description string
+	// TokenID is the token the operation concerns, for the result summary.
+	TokenID int64
+	// Build returns the calls the wallet makes for the operation.
+	Build func(wallet *sequence.Wallet[*v3.WalletConfig], call CallSpec) (sequence.Transactions, error)
+}
+
+// ParseSendOp parses the arguments of the send subcommand.
+func ParseSendOp(args []string) (*SendOp, error) {
+	fs := flag.NewFlagSet("send", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	name := fs.String("op", "mint", "operation to send: "+strings.Join(sendOpNames, ", "))
+	to := fs.String("to", "", "transfer or batch mint recipient")
+	tokenID := fs.Int64("token-id", -1, "token to transfer")
+	amount := fs.Int64("amount", 1, "amount of the token to transfer")
+	tokenIDs := fs.String("token-ids", "", "comma-separated tokens to batch mint")
+	amounts := fs.String("amounts", "", "comma-separated amounts to batch mint, one per token")
+	if err := fs.Parse(args); err != nil {
+		return nil, fmt.Errorf("%w\n%s", err, SendUsage)
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected argument %q\n%s", fs.Arg(0), SendUsage)
+	}
+
+	switch *name {
+	case "mint":
+		return &SendOp{Name: "mint"}, nil
+	case "transfer":
+		if !common.IsHexAddress(*to) {
+			return nil, fmt.Errorf("transfer: -to must be an address, got %q", *to)
+		}
+		if *tokenID < 0 {
+			return nil, errors.New("transfer: -token-id is required")
+		}
+		if *amount < 1 {
+			return nil, fmt.Errorf("transfer: -amount must be >= 1, got %d", *amount)
+		}
+		recipient, id, n := common.HexToAddress(*to), *tokenID, *amount
+		return &SendOp{
+			Name:        "transfer",
+			Description: fmt.Sprintf("transfer of %d of tokenId=%d to %s", n, id, recipient.Hex()),
+			TokenID:     id,
+			Build: func(wallet *sequence.Wallet[*v3.WalletConfig], call CallSpec) (sequence.Transactions, error) {
+				return buildTransferTransaction(wallet, call, recipient, id, n)
+			},
+		}, nil
+	case "mint-batch":
+		if *to != "" && !common.IsHexAddress(*to) {
+			return nil, fmt.Errorf("mint-batch: -to must be an address, got %q", *to)
+		}
+		ids, err := parseIntList(*tokenIDs, 0)
+		if err != nil {
+			return nil, fmt.Errorf("mint-batch: -token-ids: %w", err)
+		}
+		if len(ids) == 0 {
+			return nil, errors.New("mint-batch: -token-ids is required")
+		}
+		ns := make([]int64, len(ids))
+		for i := range ns {
+			ns[i] = 1
+		}
+		if *amounts != "" {
+			if ns, err = parseIntList(*amounts, 1); err != nil {
+				return nil, fmt.Errorf("mint-batch: -amounts: %w", err)
+			}
+			if len(ns) != len(ids) {
+				return nil, fmt.Errorf("mint-batch: %d token IDs but %d amounts", len(ids), len(ns))
+			}
+		}
+		var recipient *common.Address
+		described := "the wallet"
+		if *to != "" {
+			addr := common.HexToAddress(*to)
+			recipient, described = &addr, addr.Hex()
+		}
+		return &SendOp{
+			Name:        "mint-batch",
+			Description: fmt.Sprintf("batch mint of tokenIds %s to %s", formatIntList(ids), described),
+			TokenID:     ids[0],
+			Build: func(wallet *sequence.Wallet[*v3.WalletConfig], call CallSpec) (sequence.Transactions, error) {
+				to := wallet.Address()
+				if recipient != nil {
+					to = *recipient
+				}
+				return buildMintBatchTransaction(call, to, ids, ns)
+			},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown op %q (supported ops: %s)", *name, strings.Join(sendOpNames, ", "))
+	}
+}
+
+// parseIntList parses a comma-separated list of integers, each at least
+// least.
+func parseIntList(s string, least int64) ([]int64, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var values []int64
+	for _, field := range strings.Split(s, ",") {
+		v, err := strconv.ParseInt(strings.TrimSpace(field), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer %q", field)
+		}
+		if v < least {
+			return nil, fmt.Errorf("%d is below the minimum %d", v, least)
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// formatIntList renders values as a comma-separated list.
+func formatIntList(values []int64) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = strconv.FormatInt(v, 10)
+	}
+	return strings.Join(parts, ",")
+}
+
+// buildMintBatchTransaction builds one mintBatch(address,uint256[],uint256[],bytes)
+// call minting amounts[i] of tokenIDs[i] to recipient on call.To.
+func buildMintBatchTransaction(call CallSpec, recipient common.Address, tokenIDs, amounts []int64) (sequence.Transactions, error) {
+	if len(tokenIDs) != len(amounts) {
+		return nil, fmt.Errorf("%d token IDs but %d amounts", len(tokenIDs), len(amounts))
+	}
+	ids := make([]*big.Int, len(tokenIDs))
+	ns := make([]*big.Int, len(amounts))
+	for i := range tokenIDs {
+		ids[i] = big.NewInt(tokenIDs[i])
+		ns[i] = big.NewInt(amounts[i])
+	}
+
+	calldata, err := mintFunction.Pack("mintBatch", recipient, ids, ns, []byte{})
+	if err != nil {
+		return nil, fmt.Errorf("encode mintBatch: %w", err)
+	}
+
+	value := big.NewInt(0)
+	if call.Value != nil {
+		value = cloneBigInt(call.Value)
+	}
+
+	return sequence.Transactions{{
+		To:            call.To,
+		Value:         value,
+		GasLimit:      autoGasLimit(),
+		Data:          calldata,
+		RevertOnError: true,
+	}}, nil
+}
+
+// buildTransferTransaction builds a safeTransferFrom of amount of tokenID
+// from the wallet to recipient on the ERC-1155 contract call.To.
+func buildTransferTransaction(wallet *sequence.Wallet[*v3.WalletConfig], call CallSpec, recipient common.Address, tokenID, amount int64) (sequence.Transactions, error) {
+	calldata, err := erc1155ABI.Pack("safeTransferFrom", wallet.Address(), recipient, big.NewInt(tokenID), big.NewInt(amount), []byte{})
+	if err != nil {
+		return nil, fmt.Errorf("encode safeTransferFrom: %w", err)
+	}
+
+	return sequence.Transactions{{
+		To:            call.To,
+		Value:         big.NewInt(0),
+		GasLimit:      autoGasLimit(),
+		Data:          calldata,
+		RevertOnError: true,
+	}}, nil
+}
+
+// RunSendOp relays the transactions of op and waits for them to confirm.
+func (r *Runner) RunSendOp(ctx context.Context, opts SendOptions, call CallSpec, op *SendOp) TxResult {
+	cfg, wallet, provider := r.Config, r.Wallet, r.Provider
+	fmt.Fprintf(r.out(), "\nSending %s...\n", op.Description)
+
+	txs, err := op.Build(wallet, call)
+	if err != nil {
+		return TxResult{TokenID: op.TokenID, Err: err}
+	}
+
+	bundle, err := r.SendTransactionsWithFees(ctx, opts, txs, nil)
+	if err != nil {
+		return TxResult{TokenID: op.TokenID, Err: fmt.Errorf("relay: %w", err)}
+	}
+	if bundle.Signed != nil {
+		return TxResult{TokenID: op.TokenID, MetaTxnID: bundle.MetaTxnID, Fee: bundle.Fee, DryRun: true}
+	}
+
+	receipt, err := waitForRelayedReceipt(ctx, r.out(), cfg, provider, bundle)
+	if err != nil {
+		return TxResult{TokenID: op.TokenID, MetaTxnID: bundle.MetaTxnID, Fee: bundle.Fee, Approvals: bundle.Approvals, Err: fmt.Errorf("wait: %w", err)}
+	}
+
+	result := TxResult{
+		TokenID:      op.TokenID,
+		MetaTxnID:    bundle.MetaTxnID,
+		TxHash:       receipt.TxHash.Hex(),
+		Receipt:      receipt,
+		Fee:          bundle.Fee,
+		Approvals:    bundle.Approvals,
+		Confirmation: time.Since(bundle.SubmittedAt),
+		Calls:        decodeCallOutcomes(receipt, wallet.Address(), bundle.MetaTxnID),
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		result.Err = fmt.Errorf("transaction %s reverted", receipt.TxHash.Hex())
+		if reason, err := replayRevert(ctx, cfg, provider, receipt); err == nil {
+			result.Err = fmt.Errorf("transaction %s reverted: %s", receipt.TxHash.Hex(), reason)
+		}
+	}
+	return result
+}
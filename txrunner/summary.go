@@ -0,0 +1,220 @@
+package txrunner
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/0xsequence/ethkit/go-ethereum/common"
+	"github.com/0xsequence/ethkit/go-ethereum/core/types"
+	sequence "github.com/0xsequence/go-sequence"
+)
+
+// ---------------------------------------------------------------------------
+// Progress events
+// ---------------------------------------------------------------------------
+
+// ProgressKind identifies a phase of relaying a bundle.
+type ProgressKind int
+
+const (
+	// ProgressFeeSelected: fee options were evaluated. Fee is nil when the
+	// relayer charges no fee.
+	ProgressFeeSelected ProgressKind = iota
+	// ProgressSigned: the bundle was signed. Digest is set.
+	ProgressSigned
+	// ProgressSubmitted: the relayer accepted the bundle. MetaTxnID is set.
+	ProgressSubmitted
+	// ProgressMined: a receipt was obtained. Receipt is set.
+	ProgressMined
+	// ProgressConfirmed: the receipt reports success. Receipt is set.
+	ProgressConfirmed
+)
+
+// ProgressEvent reports a phase transition while relaying a bundle. Only the
+// fields relevant to Kind are set.
+type ProgressEvent struct {
+	Kind      ProgressKind
+	Fee       *sequence.RelayerFeeOption
+	Digest    common.Hash
+	MetaTxnID sequence.MetaTxnID
+	Receipt   *types.Receipt
+}
+
+func (ev ProgressEvent) String() string {
+	return ev.format(nil)
+}
+
+// format renders the event, printing amounts with nf.
+func (ev ProgressEvent) format(nf *NumberFormat) string {
+	switch ev.Kind {
+	case ProgressFeeSelected:
+		if ev.Fee == nil {
+			return "Fee selected: none required"
+		}
+		return fmt.Sprintf("Fee selected: %s", nf.fee(ev.Fee))
+	case ProgressSigned:
+		return fmt.Sprintf("Signed: digest %s", ev.Digest.Hex())
+	case ProgressSubmitted:
+		return fmt.Sprintf("Submitted: op hash %s", ev.MetaTxnID)
+	case ProgressMined:
+		return fmt.Sprintf("Mined: tx %s in block %s", ev.Receipt.TxHash.Hex(), ev.Receipt.BlockNumber)
+	case ProgressConfirmed:
+		return fmt.Sprintf("Confirmed: tx %s", ev.Receipt.TxHash.Hex())
+	default:
+		return fmt.Sprintf("progress(%d)", int(ev.Kind))
+	}
+}
+
+// emitProgress delivers ev to ch without blocking. Events are dropped when
+// the channel is full, so a slow consumer can never stall relaying; callers
+// that want every event should use a buffered channel. A nil ch is a no-op.
+func emitProgress(ch chan<- ProgressEvent, ev ProgressEvent) {
+	if ch == nil {
+		return
+	}
+	select {
+	case ch <- ev:
+	default:
+	}
+}
+
+// ---------------------------------------------------------------------------
+// Result summary
+// ---------------------------------------------------------------------------
+
+func PrintResultsSummary(out io.Writer, results []TxResult, cfg *Config) {
+	fmt.Fprintln(out, "\n--- Results ---")
+	fmt.Fprintf(out, "%-6s %-10s %-68s %-10s %-10s\n", "Index", "TokenID", "TxHash", "Confirmed", "Status")
+	fmt.Fprintln(out, strings.Repeat("-", 111))
+
+	succeeded, partial, failed, signed := 0, 0, 0, 0
+	var confirmed []time.Duration
+	for _, r := range results {
+		status := "OK"
+		txHash := r.TxHash
+		if r.DryRun {
+			status = "SIGNED (dry run, op hash " + r.MetaTxnID.String() + ")"
+			txHash = "-"
+			signed++
+		} else if r.Err != nil {
+			status = fmt.Sprintf("FAILED: %v", r.Err)
+			if txHash == "" {
+				txHash = "-"
+			}
+			failed++
+		} else if ok, isPartial := partialCalls(r.Calls); isPartial {
+			status = fmt.Sprintf("PARTIAL: %d of %d calls succeeded", ok, len(r.Calls))
+			partial++
+		} else {
+			succeeded++
+		}
+		elapsed := "-"
+		if r.Confirmation > 0 {
+			elapsed = formatConfirmation(r.Confirmation)
+			confirmed = append(confirmed, r.Confirmation)
+		}
+		fmt.Fprintf(out, "%-6d %-10d %-68s %-10s %s\n", r.Index+1, r.TokenID, txHash, elapsed, status)
+	}
+
+	if signed > 0 {
+		fmt.Fprintf(out, "\nTotal: %d | Signed (not relayed): %d | Failed: %d\n", len(results), signed, failed)
+	} else if partial > 0 {
+		fmt.Fprintf(out, "\nTotal: %d | Succeeded: %d | Partial: %d | Failed: %d\n", len(results), succeeded, partial, failed)
+	} else {
+		fmt.Fprintf(out, "\nTotal: %d | Succeeded: %d | Failed: %d\n", len(results), succeeded, failed)
+	}
+	if len(confirmed) > 0 {
+		slices.Sort(confirmed)
+		var total time.Duration
+		for _, d := range confirmed {
+			total += d
+		}
+		fmt.Fprintf(out, "Time to confirmation: min %s | mean %s | max %s\n",
+			formatConfirmation(confirmed[0]),
+			formatConfirmation(total/time.Duration(len(confirmed))),
+			formatConfirmation(confirmed[len(confirmed)-1]))
+	}
+	printCallBreakdown(out, results)
+	printCostBreakdown(out, results, cfg.NumberFormat)
+
+	for _, r := range results {
+		if r.Err != nil || r.DryRun {
+			continue
+		}
+		if link := cfg.explorerTxURL(r.TxHash); link != "" {
+			fmt.Fprintf(out, "Explorer: %s\n", link)
+		}
+	}
+}
+
+// printCallBreakdown lists the outcome of every call in each bundle where
+// some calls succeeded and others did not, e.g. a best-effort call that
+// reverted without reverting the bundle.
+func printCallBreakdown(w io.Writer, results []TxResult) {
+	header := false
+	for _, r := range results {
+		if _, isPartial := partialCalls(r.Calls); !isPartial {
+			continue
+		}
+		if !header {
+			fmt.Fprintln(w, "\n--- Partial bundles ---")
+			header = true
+		}
+
+		fmt.Fprintf(w, "Index %d (tokenId=%d):\n", r.Index+1, r.TokenID)
+		for _, c := range r.Calls {
+			if c.Reason != "" {
+				fmt.Fprintf(w, "  call %d: %s: %s\n", c.Index, c.Status, c.Reason)
+			} else {
+				fmt.Fprintf(w, "  call %d: %s\n", c.Index, c.Status)
+			}
+		}
+	}
+}
+
+// formatConfirmation renders a time to confirmation in seconds with
+// millisecond precision, e.g. "4.213s".
+func formatConfirmation(d time.Duration) string {
+	return fmt.Sprintf("%.3fs", d.Seconds())
+}
+
+// printCostBreakdown prints, for each transaction with a receipt, the gas it
+// used, its effective gas price, and the resulting native cost, which the
+// relayer paid, next to the fee the relayer charged the wallet.
+func printCostBreakdown(w io.Writer, results []TxResult, nf *NumberFormat) {
+	header := false
+	for _, r := range results {
+		if r.Receipt == nil {
+			continue
+		}
+		if !header {
+			fmt.Fprintln(w, "\n--- Costs ---")
+			fmt.Fprintf(w, "%-6s %-12s %-22s %-28s %s\n", "Index", "Gas Used", "Effective Gas Price", "Gas Cost (relayer)", "Fee Charged")
+			header = true
+		}
+
+		price, cost := "-", "-"
+		if r.Receipt.EffectiveGasPrice != nil {
+			price = r.Receipt.EffectiveGasPrice.String()
+			cost = nf.Native(receiptGasCost(r.Receipt))
+		}
+		fee := "none"
+		if r.Fee != nil {
+			fee = nf.fee(r.Fee)
+		}
+		fmt.Fprintf(w, "%-6d %-12d %-22s %-28s %s\n", r.Index+1, r.Receipt.GasUsed, price, cost, fee)
+	}
+}
+
+// receiptGasCost returns gas used × effective gas price, or nil when the
+// receipt has no effective gas price.
+func receiptGasCost(receipt *types.Receipt) *big.Int {
+	if receipt.EffectiveGasPrice == nil {
+		return nil
+	}
+	return new(big.Int).Mul(new(big.Int).SetUint64(receipt.GasUsed), receipt.EffectiveGasPrice)
+}
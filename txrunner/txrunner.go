@@ -0,0 +1,220 @@
+// Package txrunner sends Sequence V3 meta-transaction bundles from a
+// single-owner smart wallet. A Runner holds the wallet and the provider and
+// relayer it is connected to; it deploys the wallet when needed, attaches
+// and pays the relayer fee, and waits for each bundle to confirm. The
+// command in the repository root is a CLI over this package.
+package txrunner
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/0xsequence/ethkit/ethwallet"
+	"github.com/0xsequence/ethkit/go-ethereum/accounts/abi"
+	"github.com/0xsequence/ethkit/go-ethereum/common"
+	sequence "github.com/0xsequence/go-sequence"
+)
+
+// ---------------------------------------------------------------------------
+// Constants & ABI definitions
+// ---------------------------------------------------------------------------
+
+const (
+	DefaultConfigPath   = "config.json"
+	defaultDirectoryURL = "https://keymachine.sequence.app"
+	waitTimeout         = 5 * time.Minute
+)
+
+const defaultBalanceCacheTTL = 5 * time.Second
+
+const defaultConfirmLogPollInterval = 2 * time.Second
+
+const (
+	defaultConnectAttempts = 3
+	defaultConnectBackoff  = time.Second
+	defaultReceiptAttempts = 5
+	defaultReceiptBackoff  = 500 * time.Millisecond
+)
+
+const defaultFeeSwapSlippageBps = 50
+
+// Positions of the fee payment within a bundle.
+const (
+	feePositionFirst = "first"
+	feePositionLast  = "last"
+)
+
+const defaultFleetConcurrency = 4
+
+const defaultExplorerTxPath = "/tx/{hash}"
+
+const (
+	defaultDisplayDecimals = 6
+	nativeTokenDecimals    = 18
+)
+
+const (
+	defaultDeployGasLimit       = 3_000_000
+	defaultDeployGasBumpPercent = 25
+	defaultDeployMaxGasLimit    = 10_000_000
+	defaultDeployMaxAttempts    = 3
+)
+
+// undeployedRetryWait bounds how long a relay rejected for an undeployed
+// wallet waits for the deployment to appear before sending one itself; the
+// wallet's code is checked every undeployedRetryPoll.
+const (
+	undeployedRetryWait = time.Minute
+	undeployedRetryPoll = 2 * time.Second
+)
+
+const (
+	erc20TokenABIJSON   = `[{"inputs":[{"internalType":"address","name":"owner","type":"address"},{"internalType":"address","name":"spender","type":"address"}],"name":"allowance","outputs":[{"internalType":"uint256","name":"","type":"uint256"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"address","name":"spender","type":"address"},{"internalType":"uint256","name":"value","type":"uint256"}],"name":"approve","outputs":[{"internalType":"bool","name":"","type":"bool"}],"stateMutability":"nonpayable","type":"function"},{"inputs":[{"internalType":"address","name":"account","type":"address"}],"name":"balanceOf","outputs":[{"internalType":"uint256","name":"","type":"uint256"}],"stateMutability":"view","type":"function"},{"inputs":[],"name":"decimals","outputs":[{"internalType":"uint8","name":"","type":"uint8"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"address","name":"to","type":"address"},{"internalType":"uint256","name":"value","type":"uint256"}],"name":"transfer","outputs":[{"internalType":"bool","name":"","type":"bool"}],"stateMutability":"nonpayable","type":"function"}]`
+	swapRouterABIJSON   = `[{"inputs":[{"internalType":"uint256","name":"amountOut","type":"uint256"},{"internalType":"address[]","name":"path","type":"address[]"}],"name":"getAmountsIn","outputs":[{"internalType":"uint256[]","name":"amounts","type":"uint256[]"}],"stateMutability":"view","type":"function"},{"inputs":[{"internalType":"uint256","name":"amountOut","type":"uint256"},{"internalType":"uint256","name":"amountInMax","type":"uint256"},{"internalType":"address[]","name":"path","type":"address[]"},{"internalType":"address","name":"to","type":"address"},{"internalType":"uint256","name":"deadline","type":"uint256"}],"name":"swapTokensForExactTokens","outputs":[{"internalType":"uint256[]","name":"amounts","type":"uint256[]"}],"stateMutability":"nonpayable","type":"function"}]`
+	walletAdminABIJSON  = `[{"type":"function","name":"updateImplementation","inputs":[{"name":"_implementation","type":"address"}],"outputs":[],"stateMutability":"payable"},{"type":"function","name":"getImplementation","inputs":[],"outputs":[{"name":"","type":"address"}],"stateMutability":"view"},{"type":"function","name":"addHook","inputs":[{"name":"signature","type":"bytes4"},{"name":"implementation","type":"address"}],"outputs":[],"stateMutability":"payable"},{"type":"function","name":"removeHook","inputs":[{"name":"signature","type":"bytes4"}],"outputs":[],"stateMutability":"payable"},{"type":"function","name":"readHook","inputs":[{"name":"signature","type":"bytes4"}],"outputs":[{"name":"","type":"address"}],"stateMutability":"view"}]`
+	erc165ABIJSON       = `[{"type":"function","name":"supportsInterface","inputs":[{"name":"interfaceId","type":"bytes4"}],"outputs":[{"name":"","type":"bool"}],"stateMutability":"view"}]`
+	mintFunctionABIJSON = `[{"type":"function","name":"mint","inputs":[{"name":"to","type":"address"},{"name":"tokenId","type":"uint256"},{"name":"amount","type":"uint256"},{"name":"data","type":"bytes"}],"outputs":[],"stateMutability":"nonpayable"},{"type":"function","name":"mintBatch","inputs":[{"name":"to","type":"address"},{"name":"tokenIds","type":"uint256[]"},{"name":"amounts","type":"uint256[]"},{"name":"data","type":"bytes"}],"outputs":[],"stateMutability":"nonpayable"}]`
+	erc1155ABIJSON      = `[{"type":"function","name":"safeTransferFrom","inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"id","type":"uint256"},{"name":"amount","type":"uint256"},{"name":"data","type":"bytes"}],"outputs":[],"stateMutability":"nonpayable"}]`
+	multicall3ABIJSON   = `[{"type":"function","name":"aggregate3","inputs":[{"name":"calls","type":"tuple[]","components":[{"name":"target","type":"address"},{"name":"allowFailure","type":"bool"},{"name":"callData","type":"bytes"}]}],"outputs":[{"name":"returnData","type":"tuple[]","components":[{"name":"success","type":"bool"},{"name":"returnData","type":"bytes"}]}],"stateMutability":"payable"},{"type":"function","name":"getEthBalance","inputs":[{"name":"addr","type":"address"}],"outputs":[{"name":"balance","type":"uint256"}],"stateMutability":"view"}]`
+)
+
+var (
+	erc20TokenABI  = mustLoadABI(erc20TokenABIJSON)
+	swapRouterABI  = mustLoadABI(swapRouterABIJSON)
+	walletAdminABI = mustLoadABI(walletAdminABIJSON)
+	erc165ABI      = mustLoadABI(erc165ABIJSON)
+	mintFunction   = mustLoadABI(mintFunctionABIJSON)
+	multicall3ABI  = mustLoadABI(multicall3ABIJSON)
+	erc1155ABI     = mustLoadABI(erc1155ABIJSON)
+)
+
+// multicall3Address is where Multicall3 is deployed, at the same address on
+// every built-in chain.
+var multicall3Address = common.HexToAddress("0xcA11bde05977b3631167028862bE2a173976CA11")
+
+// multicallDisabled as multicallAddress turns off multicall batching.
+const multicallDisabled = "none"
+
+// nativeTokenSentinels lists placeholder addresses that relayers use to denote
+// the chain's native token instead of the zero address. 0xEeee...EEeE is the
+// EIP-7528 convention; 0x...1010 is the native token precompile on Polygon PoS.
+var nativeTokenSentinels = map[common.Address]struct{}{
+	common.HexToAddress("0xEeeeeEeeeEeEeeEeEeEeeEEEeeeeEeeeeeeeEEeE"): {},
+	common.HexToAddress("0x0000000000000000000000000000000000001010"): {},
+}
+
+// ---------------------------------------------------------------------------
+// Small utilities
+// ---------------------------------------------------------------------------
+
+// keyAddress returns the address key derives to, or "invalid key", so that
+// errors can tell keys apart without quoting them.
+func keyAddress(key string) string {
+	normalized, err := normalizePrivateKey(key)
+	if err != nil {
+		return "invalid key"
+	}
+	eoa, err := ethwallet.NewWalletFromPrivateKey(normalized)
+	if err != nil {
+		return "invalid key"
+	}
+	return eoa.Address().Hex()
+}
+
+func normalizePrivateKey(key string) (string, error) {
+	key = strings.TrimPrefix(strings.TrimSpace(key), "0x")
+	if len(key) != 64 {
+		return "", errors.New("private key must be 32 bytes (64 hex chars)")
+	}
+	if _, err := hex.DecodeString(key); err != nil {
+		return "", fmt.Errorf("invalid private key: %w", err)
+	}
+	return key, nil
+}
+
+func WithAccessKey(baseURL, accessKey string) string {
+	if strings.HasSuffix(baseURL, "/") {
+		return baseURL + accessKey
+	}
+	return fmt.Sprintf("%s/%s", baseURL, accessKey)
+}
+
+// isNativeFeeOption reports whether the fee option is paid in the chain's
+// native token, either by omitting the token contract or by using the zero
+// address or a well-known native sentinel address.
+func isNativeFeeOption(option *sequence.RelayerFeeOption) bool {
+	if option.Token.ContractAddress == nil {
+		return true
+	}
+	return isNativeTokenAddress(*option.Token.ContractAddress)
+}
+
+func isNativeTokenAddress(addr common.Address) bool {
+	if addr == (common.Address{}) {
+		return true
+	}
+	_, ok := nativeTokenSentinels[addr]
+	return ok
+}
+
+// autoGasLimit returns the gas limit that tells the wallet not to cap a
+// call's gas: the call may use whatever gas the bundle has left, and the
+// relayer sizes the bundle's gas when it estimates the meta-transaction.
+// Always use this (or isAutoGasLimit) instead of a literal zero.
+func autoGasLimit() *big.Int {
+	return big.NewInt(0)
+}
+
+// isAutoGasLimit reports whether v is the auto gas limit sentinel. A nil
+// limit is treated the same way.
+func isAutoGasLimit(v *big.Int) bool {
+	return v == nil || v.Sign() == 0
+}
+
+// validateGasLimit accepts the auto gas limit or a positive explicit limit.
+func validateGasLimit(v *big.Int) error {
+	if isAutoGasLimit(v) {
+		return nil
+	}
+	if v.Sign() < 0 {
+		return fmt.Errorf("gas limit must be positive or auto, got %s", v)
+	}
+	return nil
+}
+
+func formatGasLimit(v *big.Int) string {
+	if isAutoGasLimit(v) {
+		return "auto"
+	}
+	return v.String()
+}
+
+// transactionsValue sums the native value attached to txs.
+func transactionsValue(txs sequence.Transactions) *big.Int {
+	total := big.NewInt(0)
+	for _, txn := range txs {
+		if txn.Value != nil {
+			total.Add(total, txn.Value)
+		}
+	}
+	return total
+}
+
+func cloneBigInt(v *big.Int) *big.Int {
+	if v == nil {
+		return nil
+	}
+	return new(big.Int).Set(v)
+}
+
+func mustLoadABI(def string) abi.ABI {
+	parsed, err := abi.JSON(strings.NewReader(def))
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
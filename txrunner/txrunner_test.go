@@ -0,0 +1,191 @@
+package txrunner
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/0xsequence/ethkit/go-ethereum/common"
+	"github.com/0xsequence/ethkit/go-ethereum/common/hexutil"
+)
+
+// fakeNode is a JSON-RPC node holding the native balance of one wallet and
+// its ERC-20 balances and allowances. eth_call answers balanceOf and
+// allowance on the tokens, and getEthBalance and aggregate3 on multicall,
+// running each aggregate3 sub-call as it would run on its own.
+// eth_getBlockByNumber returns a header with gasLimit. Requests are counted
+// by method. Tests change the state it serves under mu.
+type fakeNode struct {
+	*httptest.Server
+
+	multicall  common.Address
+	gasLimit   uint64
+	native     *big.Int
+	balances   map[common.Address]*big.Int
+	allowances map[allowanceQuery]*big.Int
+
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func newFakeNode(t *testing.T) *fakeNode {
+	t.Helper()
+	n := &fakeNode{
+		multicall:  common.HexToAddress("0xcA11bde05977b3631167028862bE2a173976CA11"),
+		gasLimit:   30_000_000,
+		native:     big.NewInt(0),
+		balances:   map[common.Address]*big.Int{},
+		allowances: map[allowanceQuery]*big.Int{},
+		calls:      map[string]int{},
+	}
+	n.Server = httptest.NewServer(http.HandlerFunc(n.serve))
+	t.Cleanup(n.Close)
+	return n
+}
+
+func (n *fakeNode) count(method string) int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.calls[method]
+}
+
+type fakeRPCRequest struct {
+	ID     json.RawMessage   `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+func (n *fakeNode) serve(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		var reqs []fakeRPCRequest
+		json.Unmarshal(trimmed, &reqs)
+		var res []map[string]any
+		for _, req := range reqs {
+			res = append(res, n.answer(req))
+		}
+		json.NewEncoder(w).Encode(res)
+		return
+	}
+	var req fakeRPCRequest
+	json.Unmarshal(body, &req)
+	json.NewEncoder(w).Encode(n.answer(req))
+}
+
+func (n *fakeNode) answer(req fakeRPCRequest) map[string]any {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.calls[req.Method]++
+
+	res := map[string]any{"jsonrpc": "2.0", "id": req.ID}
+	switch req.Method {
+	case "eth_chainId":
+		res["result"] = "0x1"
+	case "eth_getBalance":
+		res["result"] = hexutil.EncodeBig(n.native)
+	case "eth_getBlockByNumber":
+		res["result"] = map[string]any{
+			"parentHash":       common.Hash{},
+			"sha3Uncles":       common.Hash{},
+			"miner":            common.Address{},
+			"stateRoot":        common.Hash{},
+			"transactionsRoot": common.Hash{},
+			"receiptsRoot":     common.Hash{},
+			"logsBloom":        hexutil.Encode(make([]byte, 256)),
+			"difficulty":       "0x0",
+			"number":           "0x1",
+			"gasLimit":         hexutil.EncodeUint64(n.gasLimit),
+			"gasUsed":          "0x0",
+			"timestamp":        "0x0",
+			"extraData":        "0x",
+		}
+	case "eth_call":
+		var msg struct {
+			To    common.Address `json:"to"`
+			Data  hexutil.Bytes  `json:"data"`
+			Input hexutil.Bytes  `json:"input"`
+		}
+		json.Unmarshal(req.Params[0], &msg)
+		data := msg.Data
+		if len(data) == 0 {
+			data = msg.Input
+		}
+		out, ok := n.call(msg.To, data)
+		if !ok {
+			res["error"] = map[string]any{"code": 3, "message": "execution reverted"}
+			break
+		}
+		res["result"] = hexutil.Encode(out)
+	default:
+		res["error"] = map[string]any{"code": -32601, "message": "method not found"}
+	}
+	return res
+}
+
+// call runs one contract call, reporting false for a revert.
+func (n *fakeNode) call(to common.Address, data []byte) ([]byte, bool) {
+	if len(data) < 4 {
+		return nil, false
+	}
+	word := func(v *big.Int) []byte { return common.LeftPadBytes(v.Bytes(), 32) }
+	if to == n.multicall {
+		method, err := multicall3ABI.MethodById(data[:4])
+		if err != nil {
+			return nil, false
+		}
+		args, err := method.Inputs.Unpack(data[4:])
+		if err != nil {
+			return nil, false
+		}
+		switch method.Name {
+		case "getEthBalance":
+			return word(n.native), true
+		case "aggregate3":
+			var calls []call3
+			if err := method.Inputs.Copy(&calls, args); err != nil {
+				return nil, false
+			}
+			results := make([]result3, len(calls))
+			for i, c := range calls {
+				out, ok := n.call(c.Target, c.CallData)
+				if !ok && !c.AllowFailure {
+					return nil, false
+				}
+				results[i] = result3{Success: ok, ReturnData: out}
+			}
+			out, err := method.Outputs.Pack(results)
+			return out, err == nil
+		}
+		return nil, false
+	}
+
+	balance, isToken := n.balances[to]
+	if !isToken {
+		// An address without code returns nothing.
+		return nil, true
+	}
+	method, err := erc20TokenABI.MethodById(data[:4])
+	if err != nil {
+		return nil, false
+	}
+	args, err := method.Inputs.Unpack(data[4:])
+	if err != nil {
+		return nil, false
+	}
+	switch method.Name {
+	case "balanceOf":
+		return word(balance), true
+	case "allowance":
+		allowance, ok := n.allowances[allowanceQuery{Token: to, Spender: args[1].(common.Address)}]
+		if !ok {
+			allowance = new(big.Int)
+		}
+		return word(allowance), true
+	}
+	return nil, false
+}
@@ -0,0 +1,242 @@
+package txrunner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/0xsequence/ethkit/ethcoder"
+	"github.com/0xsequence/ethkit/ethrpc"
+	"github.com/0xsequence/ethkit/ethtxn"
+	"github.com/0xsequence/ethkit/go-ethereum"
+	"github.com/0xsequence/ethkit/go-ethereum/common"
+	"github.com/0xsequence/ethkit/go-ethereum/common/hexutil"
+	"github.com/0xsequence/ethkit/go-ethereum/core/types"
+)
+
+// ---------------------------------------------------------------------------
+// Receipt waiting
+// ---------------------------------------------------------------------------
+
+// waitForReceipt blocks until the transaction is confirmed on-chain or
+// the wait timeout is reached.
+func waitForReceipt(ctx context.Context, waitFn ethtxn.WaitReceipt) (*types.Receipt, error) {
+	waitCtx, cancel := context.WithTimeout(ctx, waitTimeout)
+	defer cancel()
+
+	type receiptResult struct {
+		receipt *types.Receipt
+		err     error
+	}
+
+	resultCh := make(chan receiptResult, 1)
+	go func() {
+		receipt, err := waitFn(waitCtx)
+		resultCh <- receiptResult{receipt: receipt, err: err}
+	}()
+
+	select {
+	case <-waitCtx.Done():
+		return nil, waitCtx.Err()
+	case result := <-resultCh:
+		if result.err != nil {
+			return nil, result.err
+		}
+		return result.receipt, nil
+	}
+}
+
+// waitForRelayedReceipt waits for the relayer to report bundle mined, then
+// fetches the receipt from our own node. The node can lag behind the relayer's
+// view of the chain, so a missing receipt is retried with backoff per
+// cfg.Retry before giving up. While retrying, the relayer is asked again, and
+// if it now reports a replacement transaction, that hash is followed instead.
+// Reads that follow (balances, verification) then see the transaction's
+// effects. With cfg.ReorgCheckDelay set, the receipt is also confirmed
+// canonical; see confirmReceiptCanonical.
+func waitForRelayedReceipt(ctx context.Context, out io.Writer, cfg *Config, provider *ethrpc.Provider, bundle *RelayedBundle) (*types.Receipt, error) {
+	relayed, err := waitForReceipt(ctx, bundle.WaitReceipt)
+	if err != nil {
+		return nil, err
+	}
+	if relayed == nil {
+		return nil, fmt.Errorf("relayer reported %s done without a receipt", bundle.MetaTxnID)
+	}
+	reportedAt := time.Now()
+	txHash := relayed.TxHash
+
+	attempts := cfg.Retry.receiptAttempts()
+	backoff := cfg.Retry.receiptBackoff()
+	for attempt := 1; ; attempt++ {
+		receipt, err := provider.TransactionReceipt(ctx, txHash)
+		if err == nil {
+			if attempt > 1 {
+				fmt.Fprintf(out, "Node caught up with relayer for %s after %s\n", txHash.Hex(), time.Since(reportedAt).Round(time.Millisecond))
+			}
+			if cfg.ReorgCheckDelay > 0 {
+				return confirmReceiptCanonical(ctx, out, provider, receipt, time.Duration(cfg.ReorgCheckDelay))
+			}
+			return receipt, nil
+		}
+		if !errors.Is(err, ethereum.NotFound) {
+			return nil, fmt.Errorf("fetch receipt %s: %w", txHash.Hex(), err)
+		}
+
+		// A relayer that rebroadcasts or replaces the transaction reports the
+		// new hash from then on; follow it rather than a stale one.
+		if current, err := waitForReceipt(ctx, bundle.WaitReceipt); err == nil && current != nil && current.TxHash != txHash {
+			fmt.Fprintf(out, "Relayer replaced %s with %s for %s\n", txHash.Hex(), current.TxHash.Hex(), bundle.MetaTxnID)
+			txHash = current.TxHash
+			attempt, backoff = 0, cfg.Retry.receiptBackoff()
+			continue
+		}
+
+		if attempt >= attempts {
+			return nil, fmt.Errorf("node has no receipt for %s %s after the relayer reported it mined", txHash.Hex(), time.Since(reportedAt).Round(time.Millisecond))
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// errLogsUnavailable reports that the node can't serve the log queries a
+// confirmLog watch needs.
+var errLogsUnavailable = errors.New("log watch unavailable")
+
+// waitForConfirmLog polls eth_getLogs from fromBlock until the configured
+// confirmLog event appears with matching arguments, then returns the receipt
+// of the transaction that emitted it. A mint that reverts emits no event, so
+// it is only reported when waitTimeout expires.
+func waitForConfirmLog(ctx context.Context, out io.Writer, cfg *Config, provider *ethrpc.Provider, walletAddr, target common.Address, tokenID int64, fromBlock uint64) (*types.Receipt, error) {
+	cl := cfg.ConfirmLog
+	topic, _, err := ethcoder.EventTopicHash(cl.Event)
+	if err != nil {
+		return nil, fmt.Errorf("confirmLog.event: %w", err)
+	}
+	address := target
+	if cl.Address != "" {
+		address = common.HexToAddress(cl.Address)
+	}
+	replacer := strings.NewReplacer("{wallet}", walletAddr.Hex(), "{tokenId}", fmt.Sprint(tokenID), "{target}", target.Hex())
+	match := make(map[string]string, len(cl.Match))
+	for name, want := range cl.Match {
+		match[name] = replacer.Replace(want)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, waitTimeout)
+	defer cancel()
+	for {
+		latest, err := provider.BlockNumber(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("fetch block number: %w", err)
+		}
+		if latest >= fromBlock {
+			logs, err := provider.FilterLogs(ctx, ethereum.FilterQuery{
+				FromBlock: new(big.Int).SetUint64(fromBlock),
+				ToBlock:   new(big.Int).SetUint64(latest),
+				Addresses: []common.Address{address},
+				Topics:    [][]common.Hash{{topic}},
+			})
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", errLogsUnavailable, err)
+			}
+			for _, l := range logs {
+				if l.Removed || !confirmLogMatches(l, cl.Event, match) {
+					continue
+				}
+				fmt.Fprintf(out, "Confirmed tokenId=%d by event in tx %s (block %d)\n", tokenID, l.TxHash.Hex(), l.BlockNumber)
+				receipt, err := provider.TransactionReceipt(ctx, l.TxHash)
+				if err != nil {
+					return nil, fmt.Errorf("fetch receipt %s: %w", l.TxHash.Hex(), err)
+				}
+				if cfg.ReorgCheckDelay > 0 {
+					return confirmReceiptCanonical(ctx, out, provider, receipt, time.Duration(cfg.ReorgCheckDelay))
+				}
+				return receipt, nil
+			}
+			fromBlock = latest + 1
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("no matching event for tokenId=%d: %w", tokenID, ctx.Err())
+		case <-time.After(cl.pollInterval()):
+		}
+	}
+}
+
+// confirmLogMatches decodes l as event and reports whether each argument
+// named in match has the expected value. Addresses compare
+// case-insensitively and integers by their decimal form.
+func confirmLogMatches(l types.Log, event string, match map[string]string) bool {
+	def, values, ok, err := ethcoder.DecodeTransactionLogByEventSig(l, event)
+	if err != nil || !ok {
+		return false
+	}
+	for i, name := range def.ArgNames {
+		want, ok := match[name]
+		if !ok {
+			continue
+		}
+		var got string
+		switch v := values[i].(type) {
+		case common.Address:
+			got = v.Hex()
+		case []byte:
+			got = hexutil.Encode(v)
+		default:
+			got = fmt.Sprint(v)
+		}
+		if !strings.EqualFold(got, want) {
+			return false
+		}
+	}
+	return true
+}
+
+// confirmReceiptCanonical waits delay and re-fetches receipt to check its
+// block is still canonical. If the transaction moved to another block it is
+// re-checked there; if it was reorged out, the node is polled every delay
+// until it re-mines. It gives up after waitTimeout, reporting the
+// transaction dropped. Reorgs are reported to w.
+func confirmReceiptCanonical(ctx context.Context, w io.Writer, provider *ethrpc.Provider, receipt *types.Receipt, delay time.Duration) (*types.Receipt, error) {
+	ctx, cancel := context.WithTimeout(ctx, waitTimeout)
+	defer cancel()
+
+	dropped := false
+	for {
+		select {
+		case <-ctx.Done():
+			if dropped {
+				return nil, fmt.Errorf("transaction %s dropped after a reorg and not re-mined within %s", receipt.TxHash.Hex(), waitTimeout)
+			}
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+
+		current, err := provider.TransactionReceipt(ctx, receipt.TxHash)
+		switch {
+		case errors.Is(err, ethereum.NotFound):
+			if !dropped {
+				fmt.Fprintf(w, "Reorg: transaction %s is no longer in block %s; waiting for it to re-mine...\n", receipt.TxHash.Hex(), receipt.BlockHash.Hex())
+				dropped = true
+			}
+		case err != nil:
+			return nil, fmt.Errorf("re-check receipt %s: %w", receipt.TxHash.Hex(), err)
+		case current.BlockHash == receipt.BlockHash && !dropped:
+			return current, nil
+		default:
+			fmt.Fprintf(w, "Reorg: transaction %s re-mined in block %s (was %s); re-checking\n", receipt.TxHash.Hex(), current.BlockNumber, receipt.BlockNumber)
+			receipt, dropped = current, false
+		}
+	}
+}
@@ -0,0 +1,286 @@
+package txrunner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sync"
+
+	"github.com/0xsequence/ethkit/ethwallet"
+	"github.com/0xsequence/ethkit/go-ethereum/common"
+	"github.com/0xsequence/ethkit/go-ethereum/common/hexutil"
+	"github.com/0xsequence/ethkit/go-ethereum/crypto"
+	sequence "github.com/0xsequence/go-sequence"
+	"github.com/0xsequence/go-sequence/core"
+	v3 "github.com/0xsequence/go-sequence/core/v3"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// ---------------------------------------------------------------------------
+// Wallet construction
+// ---------------------------------------------------------------------------
+
+// errWalletMismatch is returned when the derived wallet address differs from
+// cfg.ExpectedWalletAddress.
+var errWalletMismatch = errors.New("wallet address mismatch")
+
+// CheckWalletAddress compares the derived wallet address with
+// cfg.ExpectedWalletAddress, if set. A mismatch usually means the wrong
+// private key, checkpoint or wallet context.
+func CheckWalletAddress(cfg *Config, derived common.Address) error {
+	if cfg.ExpectedWalletAddress == "" {
+		return nil
+	}
+	if expected := common.HexToAddress(cfg.ExpectedWalletAddress); derived != expected {
+		return fmt.Errorf("%w: derived %s, expected %s (check privateKey, walletCheckpoint and walletContext)", errWalletMismatch, derived.Hex(), expected.Hex())
+	}
+	return nil
+}
+
+// walletContextReport is the JSON output of the context subcommand.
+type walletContextReport struct {
+	ChainID           int64               `json:"chainId"`
+	Signer            string              `json:"signer"`
+	Wallet            string              `json:"wallet"`
+	ImageHash         string              `json:"imageHash"`
+	WalletContext     WalletContextRecord `json:"walletContext"`
+	ContextOverridden bool                `json:"contextOverridden"`
+}
+
+// PrintContextReport prints the signer, wallet address, config image hash
+// and wallet context the run would use, as text or, with asJSON, as a
+// walletContextReport, for cross-checking against other tooling that should
+// derive the same address. Nothing is read from the network. A wallet that
+// doesn't match ExpectedWalletAddress is reported after printing.
+func PrintContextReport(out io.Writer, cfg *Config, asJSON bool) error {
+	eoa, err := NewEOA(cfg)
+	if err != nil {
+		return fmt.Errorf("init signer: %w", err)
+	}
+	wallet, err := NewWallet(cfg, NewSigner(cfg, eoa))
+	if err != nil {
+		return fmt.Errorf("init wallet: %w", err)
+	}
+	imageHash := wallet.GetWalletConfig().ImageHash()
+
+	if asJSON {
+		wc, overridden := cfg.walletContext()
+		report := walletContextReport{
+			ChainID:           cfg.ChainID,
+			Signer:            eoa.Address().Hex(),
+			Wallet:            wallet.Address().Hex(),
+			ImageHash:         imageHash.Hex(),
+			WalletContext:     newWalletContextRecord(wc),
+			ContextOverridden: overridden,
+		}
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			return err
+		}
+	} else {
+		fmt.Fprintf(out, "Chain ID:             %d\n", cfg.ChainID)
+		fmt.Fprintf(out, "Signer Address (EOA): %s\n", eoa.Address().Hex())
+		fmt.Fprintf(out, "Smart Wallet Address: %s\n", wallet.Address().Hex())
+		fmt.Fprintf(out, "Image Hash:           %s\n", imageHash.Hex())
+		PrintWalletContext(out, cfg)
+	}
+
+	return CheckWalletAddress(cfg, wallet.Address())
+}
+
+// NewEOA returns the EOA for the configured private key, which signs for
+// the wallet and deploys it.
+func NewEOA(cfg *Config) (*ethwallet.Wallet, error) {
+	privateKey, _ := normalizePrivateKey(cfg.privateKey())
+	return ethwallet.NewWalletFromPrivateKey(privateKey)
+}
+
+// NewSigner wraps eoa as a wallet signer using cfg.SignatureScheme.
+func NewSigner(cfg *Config, eoa *ethwallet.Wallet) sequence.Signer {
+	if cfg.SignatureScheme == signatureSchemeEIP712 {
+		return &eip712Signer{eoa: eoa}
+	}
+	return sequence.NewSigner(eoa)
+}
+
+// eip712Signer signs the wallet's EIP-712 payload digest directly, which the
+// wallet verifies as a hash signature. sequence.NewSigner instead signs with
+// the EIP-191 prefix, verified as an eth_sign signature.
+type eip712Signer struct {
+	eoa *ethwallet.Wallet
+}
+
+func (s *eip712Signer) Address() common.Address {
+	return s.eoa.Address()
+}
+
+// SignDigest returns the signature followed by its type byte, as the wallet
+// expects from a DigestSigner.
+func (s *eip712Signer) SignDigest(ctx context.Context, digest common.Hash, optChainID ...*big.Int) ([]byte, error) {
+	sig, err := crypto.Sign(digest.Bytes(), s.eoa.PrivateKey())
+	if err != nil {
+		return nil, fmt.Errorf("sign digest: %w", err)
+	}
+	sig[64] += 27
+	return append(sig, byte(core.SignerSignatureTypeEIP712)), nil
+}
+
+// NewWallet builds the single-owner V3 wallet for signer. The wallet's
+// counterfactual address is derived from its initial configuration, which
+// includes cfg.WalletCheckpoint.
+func NewWallet(cfg *Config, signer sequence.Signer) (*sequence.Wallet[*v3.WalletConfig], error) {
+	return newOwnerWallet(cfg, signer.Address(), signer)
+}
+
+// newOwnerWallet builds owner's single-owner wallet. Without signers the
+// wallet can't sign, but can still be published and deployed.
+func newOwnerWallet(cfg *Config, owner common.Address, signers ...sequence.Signer) (*sequence.Wallet[*v3.WalletConfig], error) {
+	walletConfig := newWalletConfig(cfg, owner)
+	walletContext, _ := cfg.walletContext()
+
+	wallet, err := sequence.V3NewWallet(sequence.WalletOptions[*v3.WalletConfig]{
+		Config:  walletConfig,
+		Context: &walletContext,
+	}, signers...)
+	if err != nil {
+		return nil, err
+	}
+
+	// The factory deploys to the address derived from the image hash; make
+	// sure that matches the address the wallet will sign for.
+	derived, err := ownerWalletAddress(cfg, owner)
+	if err != nil {
+		return nil, err
+	}
+	if derived != wallet.Address() {
+		return nil, fmt.Errorf("derived wallet address %s does not match wallet address %s", derived.Hex(), wallet.Address().Hex())
+	}
+
+	return wallet, nil
+}
+
+// newWalletConfig returns the single-owner V3 configuration for owner.
+func newWalletConfig(cfg *Config, owner common.Address) *v3.WalletConfig {
+	return &v3.WalletConfig{
+		Threshold_:  1,
+		Checkpoint_: cfg.WalletCheckpoint,
+		Tree: &v3.WalletConfigTreeAddressLeaf{
+			Weight:  1,
+			Address: owner,
+		},
+	}
+}
+
+// walletAddressKey identifies a counterfactual address derivation: the
+// owner, the wallet context, and the checkpoint that salts the image hash.
+type walletAddressKey struct {
+	owner      common.Address
+	context    sequence.WalletContext
+	checkpoint uint64
+}
+
+var walletAddressCache sync.Map // walletAddressKey -> common.Address
+
+// WalletAddress returns the counterfactual address of the single-owner
+// wallet of cfg's key, without constructing a wallet. Derivations are
+// memoized per owner, wallet context, and checkpoint, so repeated calls are
+// free. It is safe for concurrent use.
+func WalletAddress(cfg *Config) (common.Address, error) {
+	eoa, err := NewEOA(cfg)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("init signer: %w", err)
+	}
+	return ownerWalletAddress(cfg, eoa.Address())
+}
+
+// ownerWalletAddress is WalletAddress for an arbitrary owner.
+func ownerWalletAddress(cfg *Config, owner common.Address) (common.Address, error) {
+	walletContext, _ := cfg.walletContext()
+	key := walletAddressKey{owner: owner, context: walletContext, checkpoint: cfg.WalletCheckpoint}
+	if addr, ok := walletAddressCache.Load(key); ok {
+		return addr.(common.Address), nil
+	}
+
+	addr, err := sequence.AddressFromWalletConfig(newWalletConfig(cfg, owner), walletContext)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("derive wallet address: %w", err)
+	}
+	walletAddressCache.Store(key, addr)
+	return addr, nil
+}
+
+// PrintWalletContext prints the wallet context the run targets, so the
+// contract versions behind the counterfactual address are on record.
+func PrintWalletContext(out io.Writer, cfg *Config) {
+	wc, overridden := cfg.walletContext()
+	source := "default V3"
+	if overridden {
+		source = "V3 with config overrides"
+	}
+	fmt.Fprintf(out, "Wallet Context:       %s\n", source)
+	fmt.Fprintf(out, "  Factory:            %s\n", wc.FactoryAddress.Hex())
+	fmt.Fprintf(out, "  Main Module:        %s\n", wc.MainModuleAddress.Hex())
+	fmt.Fprintf(out, "  Main Module Upgr.:  %s\n", wc.MainModuleUpgradableAddress.Hex())
+	fmt.Fprintf(out, "  Guest Module:       %s\n", wc.GuestModuleAddress.Hex())
+	fmt.Fprintf(out, "  Utils:              %s\n", wc.UtilsAddress.Hex())
+}
+
+// PrintDeployData writes to w what the factory is called with to deploy
+// wallet, and the address CREATE2 derives from it, for reviewing the
+// counterfactual address independently: keccak256(0xff ++ factory ++
+// imageHash ++ initCodeHash)[12:], where initCodeHash is
+// keccak256(creationCode ++ mainModule as a 32-byte word). Nothing is sent.
+func PrintDeployData(w io.Writer, wallet *sequence.Wallet[*v3.WalletConfig]) error {
+	config := wallet.GetWalletConfig()
+	wc := wallet.GetWalletContext()
+	derived, factory, deployData, err := sequence.EncodeWalletDeployment(config, wc)
+	if err != nil {
+		return fmt.Errorf("encode deployment: %w", err)
+	}
+	creationCode, err := hexutil.Decode(wc.CreationCode)
+	if err != nil {
+		return fmt.Errorf("decode creation code: %w", err)
+	}
+	initCodeHash := crypto.Keccak256Hash(creationCode, common.LeftPadBytes(wc.MainModuleAddress.Bytes(), 32))
+
+	fmt.Fprintln(w, "\n--- Wallet deployment ---")
+	fmt.Fprintf(w, "Image Hash:           %s\n", config.ImageHash().Hex())
+	fmt.Fprintf(w, "Factory:              %s\n", factory.Hex())
+	fmt.Fprintf(w, "Main Module:          %s\n", wc.MainModuleAddress.Hex())
+	fmt.Fprintf(w, "Init Code Hash:       %s\n", initCodeHash.Hex())
+	fmt.Fprintf(w, "Deploy Data:          %s\n", hexutil.Encode(deployData))
+	fmt.Fprintf(w, "Wallet Address:       %s\n", derived.Hex())
+
+	if derived != wallet.Address() {
+		return fmt.Errorf("deployment derives %s, but the wallet signs for %s", derived.Hex(), wallet.Address().Hex())
+	}
+	return nil
+}
+
+// PrintAddressQR renders addr as a QR code for funding the wallet from a
+// phone: to w when toTerminal is set, and to a PNG when pngPath
+// is non-empty. The code holds the bare address, which every wallet app can
+// scan.
+func PrintAddressQR(w io.Writer, addr common.Address, chainID int64, toTerminal bool, pngPath string) error {
+	qr, err := qrcode.New(addr.Hex(), qrcode.Medium)
+	if err != nil {
+		return fmt.Errorf("encode address: %w", err)
+	}
+
+	if toTerminal {
+		fmt.Fprintf(w, "\nScan to fund the smart wallet on chain %d:\n", chainID)
+		fmt.Fprint(w, qr.ToSmallString(false))
+	}
+
+	if pngPath != "" {
+		if err := qr.WriteFile(256, pngPath); err != nil {
+			return fmt.Errorf("write %s: %w", pngPath, err)
+		}
+		fmt.Fprintf(w, "Wrote wallet address QR code to %s\n", pngPath)
+	}
+	return nil
+}
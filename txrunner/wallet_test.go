@@ -0,0 +1,56 @@
+package txrunner
+
+import (
+	"testing"
+
+	"github.com/0xsequence/ethkit/go-ethereum/common"
+	sequence "github.com/0xsequence/go-sequence"
+	v3 "github.com/0xsequence/go-sequence/core/v3"
+)
+
+func TestWalletAddressMatchesSequence(t *testing.T) {
+	const key = "0x4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318"
+	owner := common.HexToAddress("0x2c7536E3605D9C16a7a3D7b1898e529396a65c23")
+	factory := "0x00000000000000000000000000000000000000f0"
+
+	tests := []struct {
+		name string
+		cfg  *Config
+	}{
+		{"default", &Config{PrivateKey: key}},
+		{"checkpoint", &Config{PrivateKey: key, WalletCheckpoint: 7}},
+		{"context override", &Config{PrivateKey: key, WalletContext: &WalletContextConfig{Factory: factory}}},
+	}
+	seen := map[common.Address]string{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			walletContext := sequence.V3SequenceContext()
+			if tt.cfg.WalletContext != nil {
+				walletContext.FactoryAddress = common.HexToAddress(factory)
+			}
+			want, err := sequence.AddressFromWalletConfig(&v3.WalletConfig{
+				Threshold_:  1,
+				Checkpoint_: tt.cfg.WalletCheckpoint,
+				Tree:        &v3.WalletConfigTreeAddressLeaf{Weight: 1, Address: owner},
+			}, walletContext)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// The second call is served from the cache.
+			for range 2 {
+				got, err := WalletAddress(tt.cfg)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != want {
+					t.Fatalf("got %s, want %s", got.Hex(), want.Hex())
+				}
+			}
+			if other, ok := seen[want]; ok {
+				t.Errorf("same address as %q; the cache key misses a field", other)
+			}
+			seen[want] = tt.name
+		})
+	}
+}